@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Banderas del formato binario de Order que indican qué campos opcionales siguen
+const (
+	binaryHasTimestamp byte = 1 << iota
+	binaryHasTags
+)
+
+// errBinaryTruncated indica que los datos terminan antes de completar la orden
+var errBinaryTruncated = errors.New("orden binaria truncada")
+
+// MarshalBinary codifica la orden en un formato compacto, mucho más chico que
+// JSON para volúmenes grandes. Los campos van siempre en el mismo orden:
+//
+//	ID, MerchantID, monto en centavos    varints
+//	banderas                             1 byte
+//	Timestamp (si la bandera lo indica)  segundos Unix, nanosegundos y desfase horario en segundos, varints
+//	Tags (si la bandera lo indica)       cantidad y luego clave y valor con su largo, en orden de clave
+//
+// El monto se guarda en centavos enteros, así que falla si tiene fracciones de
+// centavo o no es finito.
+func (o Order) MarshalBinary() ([]byte, error) {
+	cents := math.Round(o.Amount * 100)
+	if math.IsInf(cents, 0) || math.IsNaN(cents) || math.Abs(cents) > 1<<53 || cents/100 != o.Amount {
+		return nil, fmt.Errorf("orden %d: el monto %v no es una cantidad exacta de centavos", o.ID, o.Amount)
+	}
+
+	buf := make([]byte, 0, 3*binary.MaxVarintLen64+1)
+	buf = binary.AppendVarint(buf, int64(o.ID))
+	buf = binary.AppendVarint(buf, int64(o.MerchantID))
+	buf = binary.AppendVarint(buf, int64(cents))
+
+	var flags byte
+	if !o.Timestamp.IsZero() {
+		flags |= binaryHasTimestamp
+	}
+	if len(o.Tags) > 0 {
+		flags |= binaryHasTags
+	}
+	buf = append(buf, flags)
+
+	if flags&binaryHasTimestamp != 0 {
+		_, offset := o.Timestamp.Zone()
+		buf = binary.AppendVarint(buf, o.Timestamp.Unix())
+		buf = binary.AppendUvarint(buf, uint64(o.Timestamp.Nanosecond()))
+		buf = binary.AppendVarint(buf, int64(offset))
+	}
+	if flags&binaryHasTags != 0 {
+		keys := make([]string, 0, len(o.Tags))
+		for key := range o.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = binary.AppendUvarint(buf, uint64(len(keys)))
+		for _, key := range keys {
+			buf = appendBinaryString(buf, key)
+			buf = appendBinaryString(buf, o.Tags[key])
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodifica una orden escrita por MarshalBinary. Los
+// Timestamp se reconstruyen en UTC o en una zona fija con el desfase original,
+// como los que produce time.Parse con RFC3339.
+func (o *Order) UnmarshalBinary(data []byte) error {
+	r := binaryReader{data: data}
+	id := r.varint()
+	merchantID := r.varint()
+	cents := r.varint()
+	flags := r.byte()
+
+	var timestamp time.Time
+	if flags&binaryHasTimestamp != 0 {
+		sec := r.varint()
+		nsec := r.uvarint()
+		offset := r.varint()
+		timestamp = time.Unix(sec, int64(nsec)).UTC()
+		if offset != 0 {
+			timestamp = timestamp.In(time.FixedZone("", int(offset)))
+		}
+	}
+
+	var tags map[string]string
+	if flags&binaryHasTags != 0 {
+		n := r.uvarint()
+		// Cada etiqueta ocupa al menos dos bytes: una cantidad mayor que los
+		// datos restantes es un error y no debe reservar memoria
+		if r.err == nil && n > uint64(len(r.data)) {
+			r.err = errBinaryTruncated
+		}
+		if r.err == nil {
+			tags = make(map[string]string, n)
+		}
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			key := r.string()
+			tags[key] = r.string()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("orden binaria con %d bytes sobrantes", len(r.data))
+	}
+	*o = Order{
+		ID:         int(id),
+		Amount:     float64(cents) / 100,
+		MerchantID: int(merchantID),
+		Tags:       tags,
+		Timestamp:  timestamp,
+	}
+	return nil
+}
+
+// appendBinaryString agrega s precedida por su largo
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// binaryReader consume los campos de una orden binaria. Tras el primer error
+// las lecturas devuelven ceros y err conserva ese error.
+type binaryReader struct {
+	data []byte
+	err  error
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = errBinaryTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = errBinaryTruncated
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *binaryReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) == 0 {
+		r.err = errBinaryTruncated
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *binaryReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.data)) {
+		r.err = errBinaryTruncated
+		return ""
+	}
+	s := string(r.data[:n])
+	r.data = r.data[n:]
+	return s
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestOrderBinaryRoundTrip(t *testing.T) {
+	buenosAires := time.FixedZone("ART", -3*3600)
+	tests := []struct {
+		name  string
+		order Order
+	}{
+		{"minimal", Order{ID: 1, Amount: 0.01, MerchantID: 1}},
+		{"large ids", Order{ID: 1 << 40, Amount: 499999.99, MerchantID: 1 << 31}},
+		{"utc timestamp", Order{ID: 7, Amount: 1250.5, MerchantID: 3,
+			Timestamp: time.Date(2024, 3, 15, 10, 30, 0, 123456789, time.UTC)}},
+		{"offset timestamp and tags", Order{ID: 42, Amount: 89.9, MerchantID: 12,
+			Timestamp: time.Date(2023, 12, 31, 23, 59, 59, 0, buenosAires),
+			Tags:      map[string]string{"region": "sur", "channel": "", "ñandú": "sí"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.order.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got Order
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			want := tt.order
+			if got.ID != want.ID || got.Amount != want.Amount || got.MerchantID != want.MerchantID {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+			if !maps.Equal(got.Tags, want.Tags) {
+				t.Errorf("tags = %v, want %v", got.Tags, want.Tags)
+			}
+			// El nombre de la zona no se guarda, pero sí el instante y el desfase
+			_, gotOffset := got.Timestamp.Zone()
+			_, wantOffset := want.Timestamp.Zone()
+			if !got.Timestamp.Equal(want.Timestamp) || gotOffset != wantOffset {
+				t.Errorf("timestamp = %v, want %v", got.Timestamp, want.Timestamp)
+			}
+
+			if js, _ := json.Marshal(want); len(data) >= len(js) {
+				t.Errorf("binary form is %d bytes, JSON %d; want it smaller", len(data), len(js))
+			}
+		})
+	}
+}
+
+func TestOrderBinaryRejectsBadInput(t *testing.T) {
+	if _, err := (Order{ID: 1, Amount: 10.005, MerchantID: 1}).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary accepted a fraction of a cent")
+	}
+
+	data, err := Order{ID: 3, Amount: 15, MerchantID: 2, Tags: map[string]string{"a": "b"}}.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order Order
+	for n := range len(data) {
+		if err := order.UnmarshalBinary(data[:n]); !errors.Is(err, errBinaryTruncated) {
+			t.Errorf("%d of %d bytes: err = %v, want %v", n, len(data), err, errBinaryTruncated)
+		}
+	}
+	if err := order.UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("UnmarshalBinary accepted trailing bytes")
+	}
+}
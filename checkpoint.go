@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// checkpoint es el formato en disco de SaveCheckpoint. Partial siempre es true
+// y distingue a simple vista un checkpoint de un resultado completo.
+type checkpoint struct {
+	Partial      bool
+	Certificates []Certificate
+	Remaining    []Order
+}
+
+// SaveCheckpoint guarda en JSON el estado intermedio de un empaquetado: los
+// certificados armados hasta el momento y las órdenes que faltan procesar, por
+// ejemplo Result.Certificates y Result.Remaining de un resultado parcial.
+func SaveCheckpoint(w io.Writer, done []Certificate, remaining []Order) error {
+	return json.NewEncoder(w).Encode(checkpoint{Partial: true, Certificates: done, Remaining: remaining})
+}
+
+// LoadCheckpoint lee un checkpoint escrito por SaveCheckpoint
+func LoadCheckpoint(r io.Reader) ([]Certificate, []Order, error) {
+	var cp checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, nil, fmt.Errorf("checkpoint inválido: %w", err)
+	}
+	return cp.Certificates, cp.Remaining, nil
+}
+
+// ResumeCertificates continúa un empaquetado a partir de un checkpoint. Las
+// órdenes restantes se ordenan según opts.Strategy y se colocan con First-Fit,
+// primero en los certificados del checkpoint que todavía tienen espacio y luego
+// en certificados nuevos con IDs a continuación del mayor existente. Con la
+// estrategia por defecto, la fase de equilibrio se ejecuta una sola vez al
+// final: un resultado parcial siempre se corta antes de ella. El resultado es
+// idéntico al de una ejecución sin interrupciones, con los mismos opts. Las
+// opciones de cohesión por comerciante, EffectiveAmount y
+// StrategyMaximizeFullCerts no admiten reanudar.
+func ResumeCertificates(ctx context.Context, done []Certificate, remaining []Order, opts PackOptions) (Result, error) {
+	if opts.SingleMerchantPerCert || opts.MerchantCohesion || opts.EffectiveAmount != nil ||
+		opts.Strategy == StrategyMaximizeFullCerts {
+		return Result{}, fmt.Errorf("las opciones indicadas no admiten reanudar desde un checkpoint")
+	}
+	opts.resumeFrom = append([]Certificate{}, done...)
+	return GenerateCertificatesContext(ctx, remaining, opts)
+}
+
+// packResume empaqueta las órdenes continuando sobre los certificados de
+// opts.resumeFrom (ver ResumeCertificates)
+func packResume(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		// Mismo orden que el empaquetado original para que el resto coincida
+		if opts.Strategy == StrategyFirstFitIncreasing {
+			return orderDescending(sorted[j], sorted[i], &opts)
+		}
+		return orderDescending(sorted[i], sorted[j], &opts)
+	})
+
+	done := opts.resumeFrom
+	builders := make([]certificateBuilder, len(done))
+	for i, cert := range done {
+		builders[i] = newCertificateBuilder(&opts)
+		for _, order := range cert.Orders {
+			builders[i].add(order)
+		}
+	}
+
+	certificates, pending, err := firstFitOrdered(ctx, builders, sorted, opts, 1)
+	nextID := maxCertificateID(done) + 1
+	for i := range certificates {
+		if i < len(done) {
+			certificates[i].ID = done[i].ID
+			certificates[i].CreatedAt = done[i].CreatedAt
+			continue
+		}
+		certificates[i].ID = nextID
+		nextID++
+	}
+	return certificates, pending, err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestResumeCertificatesMatchesUninterruptedRun(t *testing.T) {
+	var orders []Order
+	for i := range 3 * contextCheckInterval {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(20 + i*37%980), MerchantID: i%40 + 1})
+	}
+
+	// Las revisiones del contexto son una cada contextCheckInterval órdenes: con
+	// dos se corta en el último bloque de la primera fase y con tres, al comenzar
+	// la fase de equilibrio
+	tests := []struct {
+		name   string
+		opts   PackOptions
+		checks int
+	}{
+		{"ffi", PackOptions{Limit: 5000, Strategy: StrategyFirstFitIncreasing}, 2},
+		{"ffd-no-balance", PackOptions{Limit: 5000, DisableBalancePhase: true}, 2},
+		{"ffd-explicit-name", PackOptions{Limit: 5000, Strategy: StrategyFirstFitDecreasing, DisableBalancePhase: true}, 2},
+		{"ffd-default", PackOptions{Limit: 5000}, 2},
+		{"ffd-default-in-balance-phase", PackOptions{Limit: 5000}, 3},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		t.Run(tt.name, func(t *testing.T) {
+			full := GenerateCertificates(orders, opts)
+
+			// Interrumpir a mitad de camino y guardar el checkpoint
+			partialOpts := opts
+			partialOpts.AllowPartial = true
+			partial, err := GenerateCertificatesContext(newCountdownContext(tt.checks, context.Canceled), orders, partialOpts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !partial.Partial || len(partial.Remaining) == 0 {
+				t.Fatalf("the interrupted run was not partial (%d remaining)", len(partial.Remaining))
+			}
+			var buf bytes.Buffer
+			if err := SaveCheckpoint(&buf, partial.Certificates, partial.Remaining); err != nil {
+				t.Fatal(err)
+			}
+
+			done, remaining, err := LoadCheckpoint(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resumed, err := ResumeCertificates(context.Background(), done, remaining, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resumed.Certificates) != len(full.Certificates) {
+				t.Fatalf("resumed run gave %d certificates, uninterrupted run %d",
+					len(resumed.Certificates), len(full.Certificates))
+			}
+			for i, cert := range full.Certificates {
+				if got := resumed.Certificates[i]; got.ID != cert.ID || !CertificatesEqual(got, cert) {
+					t.Errorf("certificate %d: resumed %d orders totalling %.2f, uninterrupted %d totalling %.2f",
+						cert.ID, len(got.Orders), got.Amount, len(cert.Orders), cert.Amount)
+				}
+			}
+			if err := VerifyCertificates(orders, resumed.Certificates, opts.Limit); err != nil {
+				t.Errorf("VerifyCertificates: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// commands asocia cada subcomando con su implementación. Todos reciben un
+// contexto, los argumentos posteriores al nombre del subcomando y la salida
+// estándar.
+var commands = map[string]func(ctx context.Context, args []string, stdout io.Writer) error{
+	"generate": runGenerate,
+	"pack":     runPack,
+	"validate": runValidate,
+	"report":   runReport,
+}
+
+// cancelable son los subcomandos que atienden la cancelación del contexto y
+// guardan un resultado parcial. Solo para ellos main cancela el contexto con
+// Ctrl-C; los demás lo ignorarían y la señal no los detendría.
+var cancelable = map[string]bool{
+	"pack": true,
+}
+
+// run despacha al subcomando indicado en args[0]. Sin subcomando se ejecuta la
+// simulación completa original.
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) > 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd(ctx, args[1:], stdout)
+		}
+	}
+	return runDemo(args, stdout)
+}
+
+// runGenerate genera órdenes y las escribe en CSV
+func runGenerate(_ context.Context, args []string, stdout io.Writer) error {
+	defaults := DefaultGenOptions()
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	merchants := fs.Int("merchants", defaults.Merchants, "cantidad de comerciantes")
+	perMerchant := fs.Int("per-merchant", defaults.OrdersPerMerchant, "órdenes por comerciante")
+	seed := fs.Int64("seed", 0, "semilla del generador (0 = basada en la hora actual)")
+	maxOrders := fs.Int("max-orders", 0, "tope de órdenes en total (0 = sin tope)")
+	profiles := fs.Bool("merchant-profiles", false, "agrupar los montos de cada comerciante alrededor de su propio monto medio")
+	out := fs.String("o", "-", "archivo CSV de salida (- para la salida estándar)")
+	configPath := fs.String("config", "", "archivo de configuración JSON (las banderas tienen prioridad)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configPath != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		set := setFlags(fs)
+		if !set["merchants"] {
+			*merchants = cfg.Gen.Merchants
+		}
+		if !set["per-merchant"] {
+			*perMerchant = cfg.Gen.OrdersPerMerchant
+		}
+		if !set["seed"] {
+			*seed = cfg.Gen.Seed
+		}
+		if !set["max-orders"] {
+			*maxOrders = cfg.Gen.MaxTotalOrders
+		}
+		if !set["merchant-profiles"] {
+			*profiles = cfg.Gen.MerchantProfiles
+		}
+	}
+
+	opts := defaults
+	opts.Merchants = *merchants
+	opts.OrdersPerMerchant = *perMerchant
+	opts.Seed = *seed
+	opts.MaxTotalOrders = *maxOrders
+	opts.MerchantProfiles = *profiles
+	// El progreso va a stderr para no mezclarse con el CSV
+	opts.OnProgress = func(merchantsDone, totalMerchants, ordersDone int) {
+		fmt.Fprintf(os.Stderr, "Generadas %d órdenes para %d de %d comerciantes\n",
+			ordersDone, merchantsDone, totalMerchants)
+	}
+
+	orders, err := generateOrders(opts)
+	if err != nil {
+		return fmt.Errorf("error al generar órdenes: %w", err)
+	}
+
+	w, err := createOutput(*out, stdout)
+	if err != nil {
+		return err
+	}
+	if err := WriteOrdersCSV(w, orders); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// runPack lee órdenes en CSV, las empaqueta y escribe los certificados en JSON.
+// Si el archivo de salida termina en .ndjson o .jsonl (opcionalmente seguido de
+// .gz) se usa JSON Lines. Si ctx se cancela a mitad del empaquetado, la salida
+// es en cambio un checkpoint marcado como parcial (ver SaveCheckpoint) con los
+// certificados completados y las órdenes pendientes.
+func runPack(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	in := fs.String("in", "-", "archivo CSV de órdenes (- para la entrada estándar)")
+	out := fs.String("o", "-", "archivo de certificados (- para la salida estándar)")
+	limit := fs.Float64("limit", defaultCertificateLimit, "monto máximo por certificado")
+	minOrder := fs.Float64("min-order", 0, "rechazar órdenes por debajo de este monto")
+	maxMerchants := fs.Int("max-merchants", 0, "máximo de comerciantes distintos por certificado (0 = sin tope)")
+	exclude := fs.String("exclude", "", "IDs de órdenes a retener, separados por comas")
+	idFormat := fs.String("id-format", "", "formato de los IDs de certificado, como CERT-%04d (vacío = el número)")
+	configPath := fs.String("config", "", "archivo de configuración JSON (las banderas tienen prioridad)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// El archivo aporta también las opciones sin bandera, como la estrategia
+	var packOpts PackOptions
+	if *configPath != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		packOpts = cfg.Pack
+		set := setFlags(fs)
+		if !set["limit"] {
+			*limit = cfg.Pack.Limit
+		}
+		if !set["min-order"] {
+			*minOrder = cfg.Pack.MinOrderAmount
+		}
+		if !set["max-merchants"] {
+			*maxMerchants = cfg.Pack.MaxMerchantsPerCert
+		}
+	}
+	excludedIDs, err := parseIDList(*exclude)
+	if err != nil {
+		return fmt.Errorf("-exclude: %w", err)
+	}
+
+	orders, err := loadOrdersFile(*in)
+	if err != nil {
+		return err
+	}
+	loaded := len(orders)
+	reportExcluded(os.Stderr, orders, excludedIDs)
+	orders = ExcludeOrders(orders, excludedIDs)
+
+	packOpts.Limit = *limit
+	packOpts.MinOrderAmount = *minOrder
+	packOpts.MaxMerchantsPerCert = *maxMerchants
+	packOpts.AllowPartial = true
+	result, err := GenerateCertificatesContext(ctx, orders, packOpts)
+	if err != nil {
+		return err
+	}
+	// Las advertencias van a stderr para no mezclarse con los certificados
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "ADVERTENCIA: %s\n", warning)
+	}
+
+	w, err := createOutput(*out, stdout)
+	if err != nil {
+		return err
+	}
+	var exportOpts ExportOptions
+	if *idFormat != "" {
+		exportOpts.IDFormat = func(id int) string {
+			return fmt.Sprintf(*idFormat, id)
+		}
+	}
+	write := func(w io.Writer, certs []Certificate) error {
+		return WriteCertificatesJSONWithOptions(w, certs, exportOpts)
+	}
+	if format := outputFormat(*out); format == ".ndjson" || format == ".jsonl" {
+		write = func(w io.Writer, certs []Certificate) error {
+			return WriteCertificatesNDJSONWithOptions(w, certs, exportOpts)
+		}
+	}
+	if result.Partial {
+		write = func(w io.Writer, certs []Certificate) error {
+			return SaveCheckpoint(w, certs, result.Remaining)
+		}
+	}
+	if err := write(w, result.Certificates); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if result.Partial {
+		return fmt.Errorf("empaquetado interrumpido: se guardó un resultado parcial con %d certificados y %d órdenes pendientes",
+			len(result.Certificates), len(result.Remaining))
+	}
+
+	// El resumen solo se muestra si la salida estándar no lleva los certificados
+	if *out != "-" {
+		fmt.Fprintf(stdout, "Se generaron %d certificados para %d órdenes (%d rechazadas, %d sin colocar, %d excluidas)\n",
+			len(result.Certificates), loaded, len(result.Rejected), len(result.Unplaceable), loaded-len(orders))
+	}
+	return nil
+}
+
+// reportExcluded escribe en w qué órdenes de orders retiene -exclude y qué IDs
+// pedidos no están en la entrada. Va a stderr para que se vea también cuando
+// los certificados salen por stdout y el resumen se omite.
+func reportExcluded(w io.Writer, orders []Order, ids map[int]bool) {
+	if len(ids) == 0 {
+		return
+	}
+	found := make(map[int]bool, len(ids))
+	for _, order := range orders {
+		if ids[order.ID] {
+			found[order.ID] = true
+		}
+	}
+	var excluded, missing []string
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		if found[id] {
+			excluded = append(excluded, strconv.Itoa(id))
+		} else {
+			missing = append(missing, strconv.Itoa(id))
+		}
+	}
+	if len(excluded) > 0 {
+		fmt.Fprintf(w, "Excluidas %d órdenes: %s\n", len(excluded), strings.Join(excluded, ", "))
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(w, "ADVERTENCIA: -exclude pide IDs que no están en la entrada: %s\n", strings.Join(missing, ", "))
+	}
+}
+
+// parseIDList interpreta una lista de IDs separados por comas, como "3,17,42".
+// Una lista vacía da un conjunto vacío.
+func parseIDList(s string) (map[int]bool, error) {
+	ids := make(map[int]bool)
+	if strings.TrimSpace(s) == "" {
+		return ids, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("ID inválido %q", field)
+		}
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+// runValidate verifica un archivo de certificados contra las órdenes originales
+func runValidate(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	ordersPath := fs.String("orders", "", "archivo CSV de órdenes")
+	certsPath := fs.String("certs", "", "archivo JSON de certificados")
+	limit := fs.Float64("limit", defaultCertificateLimit, "monto máximo por certificado")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *ordersPath == "" || *certsPath == "" {
+		return fmt.Errorf("validate requiere -orders y -certs")
+	}
+
+	orders, err := loadOrdersFile(*ordersPath)
+	if err != nil {
+		return err
+	}
+	certs, err := loadCertificatesFile(*certsPath)
+	if err != nil {
+		return err
+	}
+
+	if err := VerifyCertificates(orders, certs, *limit); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Certificados válidos: %d certificados, %d órdenes\n", len(certs), len(orders))
+	return nil
+}
+
+// runReport muestra las estadísticas de un archivo de certificados
+func runReport(_ context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	certsPath := fs.String("certs", "-", "archivo JSON de certificados (- para la entrada estándar)")
+	limit := fs.Float64("limit", defaultCertificateLimit, "monto máximo por certificado")
+	verbose := fs.Bool("v", false, "mostrar estadísticas adicionales")
+	tree := fs.Bool("tree", false, "mostrar las órdenes de cada certificado")
+	treeMax := fs.Int("tree-max", 10, "órdenes mostradas por certificado con -tree (0 = todas)")
+	margins := fs.Bool("margins", false, "mostrar el margen de cada certificado respecto del límite")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	certs, err := loadCertificatesFile(*certsPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "Estadísticas:")
+	printCertificateReport(stdout, certs, *limit, *verbose)
+	if *margins {
+		fmt.Fprintln(stdout, "\nMárgenes:")
+		printMarginReport(stdout, certs, *limit)
+	}
+	if *tree {
+		fmt.Fprintln(stdout, "\nCertificados:")
+		PrintCertificateTree(stdout, certs, *limit, *treeMax)
+	}
+	return nil
+}
+
+// loadOrdersFile lee órdenes en CSV desde un archivo o desde stdin si path es "-"
+func loadOrdersFile(path string) ([]Order, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	orders, err := LoadOrdersCSV(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return orders, nil
+}
+
+// loadCertificatesFile lee certificados en JSON desde un archivo o desde stdin si path es "-"
+func loadCertificatesFile(path string) ([]Certificate, error) {
+	r, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	certs, err := LoadCertificatesJSON(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return certs, nil
+}
+
+// openInput abre path para lectura; "-" corresponde a la entrada estándar. Los
+// archivos terminados en .gz se descomprimen al leerlos.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return gzipReadCloser{gz, f}, nil
+}
+
+// createOutput crea path para escritura; "-" corresponde a stdout. Los archivos
+// terminados en .gz se escriben comprimidos.
+func createOutput(path string, stdout io.Writer) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{stdout}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	return gzipWriteCloser{gzip.NewWriter(f), f}, nil
+}
+
+// outputFormat devuelve la extensión de path que indica su formato, ignorando
+// un sufijo .gz
+func outputFormat(path string) string {
+	return filepath.Ext(strings.TrimSuffix(path, ".gz"))
+}
+
+// gzipReadCloser descomprime un archivo y cierra ambos al terminar
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g gzipReadCloser) Close() error {
+	err := g.Reader.Close()
+	if cerr := g.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// gzipWriteCloser comprime hacia un archivo; Close vuelca el compresor antes de
+// cerrar el archivo
+type gzipWriteCloser struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (g gzipWriteCloser) Close() error {
+	err := g.Writer.Close()
+	if cerr := g.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// nopWriteCloser adapta un io.Writer cuyo cierre no corresponde a este paquete
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunPackSubcommandOnSmallCSV(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "orders.csv")
+	csv := "ID,Amount,MerchantID\n" +
+		"1,400.00,1\n" +
+		"2,350.50,2\n" +
+		"3,120.25,1\n" +
+		"4,80.00,3\n"
+	if err := os.WriteFile(csvPath, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := run(context.Background(), []string{"pack", "-in", csvPath, "-limit", "500"}, &stdout); err != nil {
+		t.Fatalf("pack: %v", err)
+	}
+
+	certs, err := LoadCertificatesJSON(&stdout)
+	if err != nil {
+		t.Fatalf("pack output is not a certificates JSON: %v", err)
+	}
+	orders := []Order{
+		{ID: 1, Amount: 400, MerchantID: 1},
+		{ID: 2, Amount: 350.5, MerchantID: 2},
+		{ID: 3, Amount: 120.25, MerchantID: 1},
+		{ID: 4, Amount: 80, MerchantID: 3},
+	}
+	if err := VerifyCertificates(orders, certs, 500); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestGzipCertificatesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certs.json.gz")
+	certs := []Certificate{
+		{ID: 1, Amount: 450.75, Orders: []Order{{ID: 1, Amount: 400, MerchantID: 1}, {ID: 2, Amount: 50.75, MerchantID: 2}}},
+		{ID: 2, Amount: 300, Orders: []Order{{ID: 3, Amount: 300, MerchantID: 1, Tags: map[string]string{"region": "norte"}}}},
+	}
+
+	w, err := createOutput(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCertificatesJSON(w, certs); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) < 2 || raw[0] != 0x1f || raw[1] != 0x8b {
+		t.Fatalf("%s is not gzip-compressed", path)
+	}
+
+	got, err := loadCertificatesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DigestCertificates(got) != DigestCertificates(certs) || got[1].Orders[0].Tags["region"] != "norte" {
+		t.Errorf("reloaded certificates = %+v, want %+v", got, certs)
+	}
+}
+
+func TestRunPackWritesPartialOutputOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	var orders []Order
+	for i := range 3 * contextCheckInterval {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*37%900), MerchantID: i%30 + 1})
+	}
+	var csv bytes.Buffer
+	if err := WriteOrdersCSV(&csv, orders); err != nil {
+		t.Fatal(err)
+	}
+	in, out := filepath.Join(dir, "orders.csv"), filepath.Join(dir, "certs.json")
+	if err := os.WriteFile(in, csv.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Como si llegara Ctrl-C después del primer bloque de órdenes
+	ctx := newCountdownContext(1, context.Canceled)
+	err := runPack(ctx, []string{"-in", in, "-o", out, "-limit", "5000"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "parcial") {
+		t.Fatalf("runPack = %v, want an error reporting the partial result", err)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatalf("no output written on cancellation: %v", err)
+	}
+	defer f.Close()
+	raw, _ := io.ReadAll(f)
+	if !bytes.Contains(raw, []byte(`"Partial":true`)) {
+		t.Errorf("output is not marked partial: %.200s", raw)
+	}
+	done, remaining, err := LoadCheckpoint(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) == 0 || len(remaining) == 0 {
+		t.Fatalf("checkpoint holds %d certificates and %d remaining orders, want both non-empty", len(done), len(remaining))
+	}
+	if missing := MissingOrders(orders, append(done, Certificate{Orders: remaining})); len(missing) != 0 {
+		t.Errorf("%d orders are neither in the certificates nor remaining", len(missing))
+	}
+}
+
+func TestRunDemoJSONFormatPrintsOnlySummary(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	const cfg = `{"merchants": 20, "ordersPerMerchant": 30, "seed": 5, "limit": 50000}`
+	if err := os.WriteFile(config, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	if err := run(context.Background(), []string{"-format", "json", "-config", config}, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	// Un único objeto JSON y nada más: ni mensajes de progreso ni estadísticas
+	dec := json.NewDecoder(&stdout)
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		t.Fatalf("stdout is not JSON: %v", err)
+	}
+	if dec.More() {
+		t.Error("stdout has more than the summary object")
+	}
+	for _, key := range []string{"Merchants", "Orders", "TotalAmount", "Limit", "Certificates",
+		"AvgFillPercent", "P50Amount", "MaxAmount", "OrdersPerCertificate", "OrdersPerSecond"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("summary lacks %q", key)
+		}
+	}
+	if fields["Merchants"] != 20.0 || fields["Orders"] != 600.0 || fields["Limit"] != 50000.0 {
+		t.Errorf("summary = %v, want 20 merchants, 600 orders and limit 50000", fields)
+	}
+	if n, _ := fields["Certificates"].(float64); n < 1 {
+		t.Errorf("summary reports %v certificates", fields["Certificates"])
+	}
+}
+
+func TestRunPackExcludeHoldsBackOrders(t *testing.T) {
+	dir := t.TempDir()
+	var orders []Order
+	for i := range 40 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(50 + i*13%200), MerchantID: i%5 + 1})
+	}
+	var csv bytes.Buffer
+	if err := WriteOrdersCSV(&csv, orders); err != nil {
+		t.Fatal(err)
+	}
+	in, out := filepath.Join(dir, "orders.csv"), filepath.Join(dir, "certs.json")
+	if err := os.WriteFile(in, csv.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// El detalle de lo excluido va a stderr
+	stderr := os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stderr = w
+	var stdout bytes.Buffer
+	args := []string{"pack", "-in", in, "-o", out, "-limit", "600", "-exclude", "3, 17,40,99"}
+	err = run(context.Background(), args, &stdout)
+	os.Stderr = stderr
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(printed), "Excluidas 3 órdenes: 3, 17, 40") {
+		t.Errorf("stderr %q does not list the excluded IDs", printed)
+	}
+	if !strings.Contains(string(printed), "no están en la entrada: 99") {
+		t.Errorf("stderr %q does not report ID 99 as missing", printed)
+	}
+
+	certs, err := loadCertificatesFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	excluded := map[int]bool{3: true, 17: true, 40: true}
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			if excluded[order.ID] {
+				t.Errorf("excluded order %d is in certificate %d", order.ID, cert.ID)
+			}
+		}
+	}
+	// El resto sí queda empaquetado, y el 99 no existe así que no cuenta
+	if err := VerifyCertificates(ExcludeOrders(orders, excluded), certs, 600); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "3 excluidas") {
+		t.Errorf("summary %q does not report the 3 excluded orders", stdout.String())
+	}
+
+	if err := run(context.Background(), []string{"pack", "-in", in, "-o", out, "-exclude", "3,x"}, io.Discard); err == nil {
+		t.Error("pack accepted a non-numeric ID in -exclude")
+	}
+}
+
+func TestCancelableCommandsExist(t *testing.T) {
+	for name := range cancelable {
+		if _, ok := commands[name]; !ok {
+			t.Errorf("cancelable lists %q, which is not a subcommand", name)
+		}
+	}
+	// La simulación sin subcomando no atiende el contexto
+	if cancelable[""] {
+		t.Error("the demo run must keep the default Ctrl-C behaviour")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"iter"
+	"sort"
+)
+
+// packByMerchant empaqueta las órdenes manteniendo juntas las de cada comerciante.
+// Recorre los comerciantes de a uno (por ID, o de mayor a menor monto total con
+// opts.SortMerchantsByTotal) con un único certificado abierto: si el comerciante
+// completo no cabe en él pero sí en uno vacío, el certificado se cierra y el
+// comerciante empieza uno nuevo. Solo se reparten entre varios certificados los
+// comerciantes cuyo total supera el límite. Como los certificados cerrados no se
+// vuelven a tocar, el llenado es menor que con First-Fit-Decreasing, pero pueden
+// entregarse a opts.OnCertificate en cuanto se cierran.
+func packByMerchant(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	maxAmount := limitWithTolerance(opts.Limit, &opts)
+	var certificates []Certificate
+	closed := 0
+	current := newCertificateBuilder(&opts)
+	closeCurrent := func() {
+		if len(current.Orders) == 0 {
+			return
+		}
+		closed++
+		cert := Certificate{
+			ID:     closed,
+			Amount: current.Amount,
+			Orders: current.Orders,
+		}
+		if opts.streamed != nil {
+			*opts.streamed++
+			opts.OnCertificate(cert)
+		} else {
+			certificates = append(certificates, cert)
+		}
+		current = newCertificateBuilder(&opts)
+	}
+
+	processed := make(map[int]bool)
+	for chunk := range merchantChunks(orders, opts.SortMerchantsByTotal, opts.ChunkMerchants) {
+		for _, group := range chunk {
+			if err := ctx.Err(); err != nil {
+				closeCurrent()
+				pending := FilterOrders(orders, func(order Order) bool {
+					return !processed[order.MerchantID]
+				})
+				return certificates, pending, err
+			}
+
+			// Evitar partir un comerciante que entraría completo en un certificado nuevo
+			if current.Amount+group.total > maxAmount && group.total <= maxAmount {
+				closeCurrent()
+			}
+			for _, order := range group.orders {
+				if len(current.Orders) > 0 && !current.fits(order, maxAmount, &opts) {
+					closeCurrent()
+				}
+				current.add(order)
+			}
+			processed[group.merchantID] = true
+		}
+	}
+	closeCurrent()
+
+	return certificates, nil, nil
+}
+
+// merchantGroup contiene las órdenes de un comerciante ordenadas de mayor a menor monto
+type merchantGroup struct {
+	merchantID int
+	total      float64
+	orders     []Order
+}
+
+// groupByMerchant agrupa las órdenes por comerciante, ordenando los grupos por
+// ID de comerciante o, con byTotal, de mayor a menor monto total
+func groupByMerchant(orders []Order, byTotal bool) []merchantGroup {
+	index := make(map[int]int)
+	var groups []merchantGroup
+	for _, order := range orders {
+		i, ok := index[order.MerchantID]
+		if !ok {
+			i = len(groups)
+			index[order.MerchantID] = i
+			groups = append(groups, merchantGroup{merchantID: order.MerchantID})
+		}
+		groups[i].orders = append(groups[i].orders, order)
+		groups[i].total += order.Amount
+	}
+
+	for _, group := range groups {
+		sort.Slice(group.orders, func(i, j int) bool {
+			return amountDescending(group.orders[i], group.orders[j])
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if byTotal && groups[i].total != groups[j].total {
+			return groups[i].total > groups[j].total
+		}
+		return groups[i].merchantID < groups[j].merchantID
+	})
+	return groups
+}
+
+// merchantChunks entrega los grupos de comerciantes en el orden de
+// groupByMerchant, de a size comerciantes por lote. Cada lote se arma recorriendo
+// orders, de modo que en memoria solo están agrupadas las órdenes del lote
+// actual. Con size <= 0 entrega todos los grupos en un único lote.
+func merchantChunks(orders []Order, byTotal bool, size int) iter.Seq[[]merchantGroup] {
+	return func(yield func([]merchantGroup) bool) {
+		if size <= 0 {
+			yield(groupByMerchant(orders, byTotal))
+			return
+		}
+
+		// Primero solo los totales, para decidir el orden de los comerciantes
+		totals := make(map[int]float64)
+		var merchants []int
+		for _, order := range orders {
+			if _, ok := totals[order.MerchantID]; !ok {
+				merchants = append(merchants, order.MerchantID)
+			}
+			totals[order.MerchantID] += order.Amount
+		}
+		sort.Slice(merchants, func(i, j int) bool {
+			a, b := merchants[i], merchants[j]
+			if byTotal && totals[a] != totals[b] {
+				return totals[a] > totals[b]
+			}
+			return a < b
+		})
+
+		for start := 0; start < len(merchants); start += size {
+			batch := merchants[start:min(start+size, len(merchants))]
+			index := make(map[int]int, len(batch))
+			chunk := make([]merchantGroup, len(batch))
+			for i, merchantID := range batch {
+				index[merchantID] = i
+				chunk[i] = merchantGroup{merchantID: merchantID}
+			}
+			for _, order := range orders {
+				if i, ok := index[order.MerchantID]; ok {
+					chunk[i].orders = append(chunk[i].orders, order)
+					chunk[i].total += order.Amount
+				}
+			}
+			for _, group := range chunk {
+				sort.Slice(group.orders, func(i, j int) bool {
+					return amountDescending(group.orders[i], group.orders[j])
+				})
+			}
+			if !yield(chunk) {
+				return
+			}
+		}
+	}
+}
+
+// packSingleMerchant empaqueta cada comerciante por separado con
+// First-Fit-Decreasing, de modo que ningún certificado mezcla comerciantes
+// aunque eso deje capacidad sin usar
+func packSingleMerchant(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	var certificates []Certificate
+	groups := groupByMerchant(orders, opts.SortMerchantsByTotal)
+	for i, group := range groups {
+		if err := ctx.Err(); err != nil {
+			var pending []Order
+			for _, rest := range groups[i:] {
+				pending = append(pending, rest.orders...)
+			}
+			return certificates, pending, err
+		}
+		certificates = append(certificates, firstFitDecreasing(group.orders, opts, len(certificates)+1)...)
+	}
+	return certificates, nil, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestSortMerchantsByTotalLargestFirst(t *testing.T) {
+	// El comercio 3 llega último pero es el de mayor monto total (900)
+	orders := []Order{
+		{ID: 1, Amount: 200, MerchantID: 1},
+		{ID: 2, Amount: 150, MerchantID: 1},
+		{ID: 3, Amount: 400, MerchantID: 2},
+		{ID: 4, Amount: 450, MerchantID: 3},
+		{ID: 5, Amount: 450, MerchantID: 3},
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: 1000, MerchantCohesion: true, SortMerchantsByTotal: true})
+
+	var first *Certificate
+	for i := range result.Certificates {
+		if result.Certificates[i].ID == 1 {
+			first = &result.Certificates[i]
+		}
+	}
+	if first == nil {
+		t.Fatal("no certificate with ID 1")
+	}
+	for _, order := range first.Orders {
+		if order.MerchantID != 3 {
+			t.Errorf("certificate 1 holds order %d of merchant %d, want only merchant 3", order.ID, order.MerchantID)
+		}
+	}
+	if len(first.Orders) != 2 {
+		t.Errorf("certificate 1 holds %d orders, want both of merchant 3", len(first.Orders))
+	}
+}
+
+func TestSingleMerchantPerCertNeverMixes(t *testing.T) {
+	// Órdenes chicas que sin la restricción entrarían todas en un solo certificado
+	var orders []Order
+	for i := range 20 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(20 + i*7), MerchantID: i%5 + 1})
+	}
+	// Un comercio con más de un certificado de órdenes
+	for i := range 4 {
+		orders = append(orders, Order{ID: 100 + i, Amount: 400, MerchantID: 6})
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: 1000, SingleMerchantPerCert: true})
+
+	for _, cert := range result.Certificates {
+		for _, order := range cert.Orders {
+			if order.MerchantID != cert.Orders[0].MerchantID {
+				t.Errorf("certificate %d mixes merchants %d and %d", cert.ID, cert.Orders[0].MerchantID, order.MerchantID)
+			}
+		}
+	}
+	if len(result.Certificates) != 7 {
+		t.Errorf("got %d certificates, want one per small merchant plus two for merchant 6", len(result.Certificates))
+	}
+	if err := VerifyCertificates(orders, result.Certificates, 1000); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestChunkMerchantsMatchesUnchunkedCohesion(t *testing.T) {
+	orders, err := generateOrders(GenOptions{Merchants: 23, OrdersPerMerchant: 40, Seed: 5, MerchantProfiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sortByTotal := range []bool{false, true} {
+		base := PackOptions{Limit: 8000, MerchantCohesion: true, SortMerchantsByTotal: sortByTotal}
+		want := GenerateCertificates(orders, base).Certificates
+
+		for _, chunk := range []int{1, 4, 10, 23, 100} {
+			opts := base
+			opts.ChunkMerchants = chunk
+			got := GenerateCertificates(orders, opts).Certificates
+			if DigestCertificates(got) != DigestCertificates(want) {
+				t.Errorf("sort %v, chunk %d: got %d certificates, different from the %d of the unchunked run",
+					sortByTotal, chunk, len(got), len(want))
+			}
+
+			// Entregados lote a lote por OnCertificate también coinciden
+			var delivered []Certificate
+			opts.OnCertificate = func(cert Certificate) { delivered = append(delivered, cert) }
+			GenerateCertificates(orders, opts)
+			if DigestCertificates(delivered) != DigestCertificates(want) {
+				t.Errorf("sort %v, chunk %d: OnCertificate delivered %d certificates, want the %d of the unchunked run",
+					sortByTotal, chunk, len(delivered), len(want))
+			}
+		}
+	}
+}
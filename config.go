@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Config reúne las opciones de generación y empaquetado de un archivo de
+// configuración (ver LoadConfig)
+type Config struct {
+	Gen  GenOptions
+	Pack PackOptions
+}
+
+// configFile es el formato del archivo de LoadConfig
+type configFile struct {
+	Merchants         int   `json:"merchants"`
+	OrdersPerMerchant int   `json:"ordersPerMerchant"`
+	Seed              int64 `json:"seed"`
+	MaxTotalOrders    int   `json:"maxTotalOrders"`
+	MerchantProfiles  bool  `json:"merchantProfiles"`
+
+	Limit                 float64  `json:"limit"`
+	Strategy              Strategy `json:"strategy"`
+	MinOrderAmount        float64  `json:"minOrderAmount"`
+	OverflowLimit         float64  `json:"overflowLimit"`
+	MaxMerchantsPerCert   int      `json:"maxMerchantsPerCert"`
+	MerchantCohesion      bool     `json:"merchantCohesion"`
+	SingleMerchantPerCert bool     `json:"singleMerchantPerCert"`
+	MinCertAmount         float64  `json:"minCertAmount"`
+	DisableBalancePhase   bool     `json:"disableBalancePhase"`
+}
+
+// LoadConfig lee de path un objeto JSON con las opciones de generación y
+// empaquetado, en lugar de indicarlas con banderas:
+//
+//	{"merchants": 100, "ordersPerMerchant": 50, "seed": 42, "maxTotalOrders": 0,
+//	 "merchantProfiles": false, "limit": 250000, "strategy": "ffd",
+//	 "minOrderAmount": 0, "overflowLimit": 0, "maxMerchantsPerCert": 0, "merchantCohesion": false,
+//	 "singleMerchantPerCert": false, "minCertAmount": 0, "disableBalancePhase": false}
+//
+// Todos los campos son opcionales: los ausentes toman los valores de
+// DefaultGenOptions y el límite por defecto. Rechaza campos desconocidos,
+// cantidades negativas y estrategias desconocidas.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	gen := DefaultGenOptions()
+	file := configFile{
+		Merchants:         gen.Merchants,
+		OrdersPerMerchant: gen.OrdersPerMerchant,
+		Limit:             defaultCertificateLimit,
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return Config{}, fmt.Errorf("configuración %s inválida: %w", path, err)
+	}
+
+	gen.Merchants = file.Merchants
+	gen.OrdersPerMerchant = file.OrdersPerMerchant
+	gen.Seed = file.Seed
+	gen.MaxTotalOrders = file.MaxTotalOrders
+	gen.MerchantProfiles = file.MerchantProfiles
+	if err := gen.validate(); err != nil {
+		return Config{}, fmt.Errorf("configuración %s: %w", path, err)
+	}
+	switch {
+	case file.Strategy != "" && file.Strategy != StrategyFirstFitDecreasing && file.Strategy != StrategyFirstFitIncreasing &&
+		file.Strategy != StrategyMaximizeFullCerts:
+		return Config{}, fmt.Errorf("configuración %s: estrategia de empaquetado desconocida: %q", path, file.Strategy)
+	case file.Limit <= 0 || file.MinOrderAmount < 0 || file.OverflowLimit < 0 ||
+		file.MaxMerchantsPerCert < 0 || file.MinCertAmount < 0:
+		return Config{}, fmt.Errorf("configuración %s: los montos y topes no pueden ser negativos y el límite debe ser positivo", path)
+	}
+
+	return Config{
+		Gen: gen,
+		Pack: PackOptions{
+			Limit:                 file.Limit,
+			Strategy:              file.Strategy,
+			MinOrderAmount:        file.MinOrderAmount,
+			OverflowLimit:         file.OverflowLimit,
+			MaxMerchantsPerCert:   file.MaxMerchantsPerCert,
+			MerchantCohesion:      file.MerchantCohesion,
+			SingleMerchantPerCert: file.SingleMerchantPerCert,
+			MinCertAmount:         file.MinCertAmount,
+			DisableBalancePhase:   file.DisableBalancePhase,
+		},
+	}, nil
+}
+
+// setFlags devuelve los nombres de las banderas indicadas en la línea de
+// comandos, que tienen prioridad sobre el archivo de configuración
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
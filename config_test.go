@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig escribe contents en un archivo de configuración temporal
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigSmallFile(t *testing.T) {
+	path := writeConfig(t, `{
+		"merchants": 12,
+		"ordersPerMerchant": 7,
+		"seed": 99,
+		"merchantProfiles": true,
+		"limit": 250000,
+		"strategy": "full",
+		"maxMerchantsPerCert": 4,
+		"merchantCohesion": true,
+		"minCertAmount": 1000
+	}`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	gen := cfg.Gen
+	if gen.Merchants != 12 || gen.OrdersPerMerchant != 7 || gen.Seed != 99 || !gen.MerchantProfiles || gen.MaxTotalOrders != 0 {
+		t.Errorf("Gen = %+v, want 12 merchants, 7 orders each, seed 99, profiles on, no order cap", gen)
+	}
+	// Lo que el archivo no fija sigue con los valores por defecto
+	if want := DefaultGenOptions().ProgressEvery; gen.ProgressEvery != want {
+		t.Errorf("ProgressEvery = %d, want the default %d", gen.ProgressEvery, want)
+	}
+
+	pack := cfg.Pack
+	if pack.Limit != 250000 || pack.Strategy != StrategyMaximizeFullCerts || pack.MaxMerchantsPerCert != 4 ||
+		!pack.MerchantCohesion || pack.MinCertAmount != 1000 {
+		t.Errorf("Pack = %+v, want limit 250000, strategy full, 4 merchants per certificate, cohesion, min 1000", pack)
+	}
+	if pack.MinOrderAmount != 0 || pack.OverflowLimit != 0 || pack.SingleMerchantPerCert || pack.DisableBalancePhase {
+		t.Errorf("Pack = %+v, want the options absent from the file left unset", pack)
+	}
+
+	// Un archivo vacío deja todo por defecto, con el límite por defecto
+	cfg, err = LoadConfig(writeConfig(t, `{}`))
+	if err != nil {
+		t.Fatalf("LoadConfig({}): %v", err)
+	}
+	if defaults := DefaultGenOptions(); cfg.Gen.Merchants != defaults.Merchants || cfg.Gen.OrdersPerMerchant != defaults.OrdersPerMerchant {
+		t.Errorf("Gen = %+v, want the defaults", cfg.Gen)
+	}
+	if cfg.Pack.Limit != defaultCertificateLimit {
+		t.Errorf("Limit = %.2f, want the default %.2f", cfg.Pack.Limit, float64(defaultCertificateLimit))
+	}
+}
+
+func TestLoadConfigRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"unknown-field", `{"merchnts": 10}`},
+		{"unknown-strategy", `{"strategy": "best-fit"}`},
+		{"negative-limit", `{"limit": -1}`},
+		{"zero-limit", `{"limit": 0}`},
+		{"negative-min-cert", `{"minCertAmount": -5}`},
+		{"negative-merchants", `{"merchants": -3}`},
+		{"not-json", `merchants: 10`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, tt.contents)
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("LoadConfig accepted an invalid file")
+			}
+			if !strings.Contains(err.Error(), path) {
+				t.Errorf("error %q does not name the file", err)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadConfig accepted a missing file")
+	}
+}
+
+func TestConfigFlagsOverrideFileValues(t *testing.T) {
+	path := writeConfig(t, `{"merchants": 3, "ordersPerMerchant": 5, "seed": 11}`)
+
+	generate := func(args ...string) []Order {
+		t.Helper()
+		var stdout bytes.Buffer
+		if err := run(context.Background(), append([]string{"generate", "-config", path}, args...), &stdout); err != nil {
+			t.Fatalf("generate %v: %v", args, err)
+		}
+		orders, err := LoadOrdersCSV(&stdout)
+		if err != nil {
+			t.Fatalf("generate output is not an orders CSV: %v", err)
+		}
+		return orders
+	}
+	merchantCount := func(orders []Order) int {
+		merchants := make(map[int]bool)
+		for _, order := range orders {
+			merchants[order.MerchantID] = true
+		}
+		return len(merchants)
+	}
+
+	// Solo el archivo: 3 comerciantes con 5 órdenes cada uno
+	fromFile := generate()
+	if len(fromFile) != 15 || merchantCount(fromFile) != 3 {
+		t.Errorf("got %d orders from %d merchants, want 15 from 3", len(fromFile), merchantCount(fromFile))
+	}
+
+	// -per-merchant gana sobre el archivo; merchants y seed siguen del archivo
+	overridden := generate("-per-merchant", "2")
+	if len(overridden) != 6 || merchantCount(overridden) != 3 {
+		t.Errorf("got %d orders from %d merchants, want 6 from 3", len(overridden), merchantCount(overridden))
+	}
+	if again := generate("-per-merchant", "2"); !reflect.DeepEqual(again, overridden) {
+		t.Error("the seed from the file does not make generation repeatable")
+	}
+
+	// En pack, -limit gana sobre el límite del archivo
+	ordersPath := filepath.Join(t.TempDir(), "orders.csv")
+	csv := "ID,Amount,MerchantID\n1,600.00,1\n2,500.00,2\n3,400.00,3\n"
+	if err := os.WriteFile(ordersPath, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packPath := writeConfig(t, `{"limit": 700}`)
+	pack := func(args ...string) int {
+		t.Helper()
+		var stdout bytes.Buffer
+		if err := run(context.Background(), append([]string{"pack", "-in", ordersPath, "-config", packPath}, args...), &stdout); err != nil {
+			t.Fatalf("pack %v: %v", args, err)
+		}
+		certs, err := LoadCertificatesJSON(&stdout)
+		if err != nil {
+			t.Fatalf("pack output is not a certificates JSON: %v", err)
+		}
+		return len(certs)
+	}
+	if n := pack(); n != 3 {
+		t.Errorf("limit 700 from the file gives %d certificates, want 3", n)
+	}
+	if n := pack("-limit", "1500"); n != 1 {
+		t.Errorf("-limit 1500 over the file gives %d certificates, want 1", n)
+	}
+}
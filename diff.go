@@ -0,0 +1,143 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrderMove describe una orden que cambió de certificado entre dos empaquetados.
+// Un certificado 0 indica que la orden no aparece en ese lado de la comparación.
+type OrderMove struct {
+	OrderID int
+	From    int
+	To      int
+}
+
+// CertificateDiff resume qué cambió entre dos conjuntos de certificados
+type CertificateDiff struct {
+	Moved   map[int]OrderMove // Órdenes que cambiaron de certificado, por ID de orden
+	Added   []int             // IDs de certificados que solo existen en el nuevo conjunto
+	Removed []int             // IDs de certificados que solo existen en el anterior
+}
+
+// Empty indica si ambos conjuntos asignan las órdenes de la misma forma
+func (d CertificateDiff) Empty() bool {
+	return len(d.Moved) == 0 && len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DiffCertificates compara dos empaquetados y reporta las órdenes que cambiaron
+// de certificado junto con los certificados agregados y eliminados
+func DiffCertificates(before, after []Certificate) CertificateDiff {
+	oldAssignment := assignmentByOrder(before)
+	newAssignment := assignmentByOrder(after)
+
+	diff := CertificateDiff{Moved: make(map[int]OrderMove)}
+
+	for orderID, from := range oldAssignment {
+		if to := newAssignment[orderID]; to != from {
+			diff.Moved[orderID] = OrderMove{OrderID: orderID, From: from, To: to}
+		}
+	}
+	// Órdenes que solo aparecen en el nuevo conjunto
+	for orderID, to := range newAssignment {
+		if _, ok := oldAssignment[orderID]; !ok {
+			diff.Moved[orderID] = OrderMove{OrderID: orderID, To: to}
+		}
+	}
+
+	oldIDs := make(map[int]bool, len(before))
+	for _, cert := range before {
+		oldIDs[cert.ID] = true
+	}
+	newIDs := make(map[int]bool, len(after))
+	for _, cert := range after {
+		newIDs[cert.ID] = true
+		if !oldIDs[cert.ID] {
+			diff.Added = append(diff.Added, cert.ID)
+		}
+	}
+	for _, cert := range before {
+		if !newIDs[cert.ID] {
+			diff.Removed = append(diff.Removed, cert.ID)
+		}
+	}
+	sort.Ints(diff.Added)
+	sort.Ints(diff.Removed)
+
+	return diff
+}
+
+// assignmentByOrder devuelve el ID de certificado asignado a cada orden
+func assignmentByOrder(certs []Certificate) map[int]int {
+	assignment := make(map[int]int)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			assignment[order.ID] = cert.ID
+		}
+	}
+	return assignment
+}
+
+// withinCent indica si dos montos difieren en un centavo o menos. El margen de
+// defaultEpsilon absorbe el error de la resta, como en 0.10 - 0.09.
+func withinCent(a, b float64) bool {
+	return math.Abs(a-b) <= 0.01+defaultEpsilon
+}
+
+// CertificatesEqual indica si dos certificados son equivalentes: contienen las
+// mismas órdenes, por ID y sin importar el orden, y sus montos difieren en un
+// centavo o menos. No compara el ID del certificado ni CreatedAt.
+func CertificatesEqual(a, b Certificate) bool {
+	return withinCent(a.Amount, b.Amount) &&
+		slices.Equal(sortedOrderIDs(a), sortedOrderIDs(b))
+}
+
+// CertificateSetsEqual indica si dos conjuntos de certificados son
+// equivalentes sin importar su orden: cada certificado de a se corresponde con
+// uno distinto de b según CertificatesEqual
+func CertificateSetsEqual(a, b []Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	// Certificados de a sin pareja, agrupados por sus órdenes
+	unmatched := make(map[string][]float64, len(a))
+	for _, cert := range a {
+		key := orderIDsKey(cert)
+		unmatched[key] = append(unmatched[key], cert.Amount)
+	}
+	for _, cert := range b {
+		key := orderIDsKey(cert)
+		amounts := unmatched[key]
+		i := slices.IndexFunc(amounts, func(amount float64) bool {
+			return withinCent(amount, cert.Amount)
+		})
+		if i < 0 {
+			return false
+		}
+		unmatched[key] = slices.Delete(amounts, i, i+1)
+	}
+	return true
+}
+
+// sortedOrderIDs devuelve los IDs de las órdenes del certificado de menor a mayor
+func sortedOrderIDs(cert Certificate) []int {
+	ids := make([]int, len(cert.Orders))
+	for i, order := range cert.Orders {
+		ids[i] = order.ID
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// orderIDsKey identifica el conjunto de órdenes de un certificado
+func orderIDsKey(cert Certificate) string {
+	var b strings.Builder
+	for _, id := range sortedOrderIDs(cert) {
+		b.WriteString(strconv.Itoa(id))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
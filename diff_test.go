@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffCertificatesReportsSingleMove(t *testing.T) {
+	before := []Certificate{
+		{ID: 1, Amount: 300, Orders: []Order{{ID: 10, Amount: 200, MerchantID: 1}, {ID: 11, Amount: 100, MerchantID: 2}}},
+		{ID: 2, Amount: 150, Orders: []Order{{ID: 12, Amount: 150, MerchantID: 1}}},
+	}
+	// La orden 11 pasa del certificado 1 al 2; todo lo demás queda igual
+	after := []Certificate{
+		{ID: 1, Amount: 200, Orders: []Order{{ID: 10, Amount: 200, MerchantID: 1}}},
+		{ID: 2, Amount: 250, Orders: []Order{{ID: 12, Amount: 150, MerchantID: 1}, {ID: 11, Amount: 100, MerchantID: 2}}},
+	}
+
+	diff := DiffCertificates(before, after)
+	want := map[int]OrderMove{11: {OrderID: 11, From: 1, To: 2}}
+	if !reflect.DeepEqual(diff.Moved, want) {
+		t.Errorf("Moved = %v, want %v", diff.Moved, want)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("Added = %v, Removed = %v, want both empty", diff.Added, diff.Removed)
+	}
+	if diff.Empty() {
+		t.Error("Empty() = true for a diff with a move")
+	}
+}
+
+func TestDiffCertificatesAddedAndRemoved(t *testing.T) {
+	before := []Certificate{
+		{ID: 1, Amount: 100, Orders: []Order{{ID: 1, Amount: 100, MerchantID: 1}}},
+		{ID: 2, Amount: 50, Orders: []Order{{ID: 2, Amount: 50, MerchantID: 1}}},
+	}
+	after := []Certificate{
+		{ID: 1, Amount: 150, Orders: []Order{{ID: 1, Amount: 100, MerchantID: 1}, {ID: 2, Amount: 50, MerchantID: 1}}},
+		{ID: 3, Amount: 70, Orders: []Order{{ID: 3, Amount: 70, MerchantID: 2}}},
+	}
+
+	diff := DiffCertificates(before, after)
+	if !reflect.DeepEqual(diff.Added, []int{3}) || !reflect.DeepEqual(diff.Removed, []int{2}) {
+		t.Errorf("Added = %v, Removed = %v, want [3] and [2]", diff.Added, diff.Removed)
+	}
+	want := map[int]OrderMove{
+		2: {OrderID: 2, From: 2, To: 1},
+		3: {OrderID: 3, From: 0, To: 3}, // Orden nueva
+	}
+	if !reflect.DeepEqual(diff.Moved, want) {
+		t.Errorf("Moved = %v, want %v", diff.Moved, want)
+	}
+}
+
+func TestDiffCertificatesIdenticalIsEmpty(t *testing.T) {
+	certs := []Certificate{{ID: 1, Amount: 10, Orders: []Order{{ID: 1, Amount: 10, MerchantID: 1}}}}
+	if diff := DiffCertificates(certs, certs); !diff.Empty() {
+		t.Errorf("DiffCertificates of identical sets = %+v, want empty", diff)
+	}
+}
+
+func TestCertificatesEqualIgnoresOrderSequence(t *testing.T) {
+	a := Certificate{ID: 1, Amount: 450.10, Orders: []Order{
+		{ID: 3, Amount: 200, MerchantID: 1},
+		{ID: 7, Amount: 150.10, MerchantID: 2},
+		{ID: 9, Amount: 100, MerchantID: 1},
+	}}
+	// Mismas órdenes en otro orden, con otro ID y un monto acumulado con error
+	// de redondeo
+	b := Certificate{ID: 4, Amount: 450.1000001, Orders: []Order{a.Orders[2], a.Orders[0], a.Orders[1]}}
+	if !CertificatesEqual(a, b) {
+		t.Error("CertificatesEqual = false for the same orders in a different sequence")
+	}
+
+	// Justo un centavo de diferencia sigue siendo el mismo certificado
+	for _, amount := range []float64{450.09, 450.11} {
+		if !CertificatesEqual(a, Certificate{Amount: amount, Orders: b.Orders}) {
+			t.Errorf("CertificatesEqual = false for amounts 450.10 and %.2f, one cent apart", amount)
+		}
+	}
+	cents := Certificate{Amount: 0.10, Orders: b.Orders}
+	if !CertificatesEqual(cents, Certificate{Amount: 0.09, Orders: b.Orders}) {
+		t.Error("CertificatesEqual = false for amounts 0.10 and 0.09, one cent apart")
+	}
+
+	tests := []struct {
+		name string
+		b    Certificate
+	}{
+		{"amount-off-by-two-cents", Certificate{Amount: 450.12, Orders: b.Orders}},
+		{"amount-off-by-a-cent-and-a-half", Certificate{Amount: 450.115, Orders: b.Orders}},
+		{"missing-order", Certificate{Amount: 450.10, Orders: b.Orders[:2]}},
+		{"other-order", Certificate{Amount: 450.10, Orders: []Order{a.Orders[0], a.Orders[1], {ID: 10, Amount: 100}}}},
+	}
+	for _, tt := range tests {
+		if CertificatesEqual(a, tt.b) {
+			t.Errorf("%s: CertificatesEqual = true, want false", tt.name)
+		}
+	}
+}
+
+func TestCertificateSetsEqualUnordered(t *testing.T) {
+	x := Certificate{ID: 1, Amount: 300, Orders: []Order{{ID: 1, Amount: 200}, {ID: 2, Amount: 100}}}
+	y := Certificate{ID: 2, Amount: 50, Orders: []Order{{ID: 3, Amount: 50}}}
+	// Dos certificados vacíos con el mismo monto: cada uno necesita su pareja
+	empty := Certificate{ID: 3}
+
+	xReordered := Certificate{ID: 9, Amount: 300, Orders: []Order{x.Orders[1], x.Orders[0]}}
+	if !CertificateSetsEqual([]Certificate{x, y, empty, empty}, []Certificate{empty, y, empty, xReordered}) {
+		t.Error("CertificateSetsEqual = false for the same certificates in a different sequence")
+	}
+	if CertificateSetsEqual([]Certificate{x, y}, []Certificate{x, x}) {
+		t.Error("CertificateSetsEqual = true when one certificate is repeated in place of another")
+	}
+	if CertificateSetsEqual([]Certificate{x, y}, []Certificate{x}) {
+		t.Error("CertificateSetsEqual = true for sets of different size")
+	}
+}
@@ -0,0 +1,8 @@
+// Command fcb agrupa órdenes de comerciantes en certificados cuyo monto no supera
+// un límite, y ofrece subcomandos para generar, empaquetar, validar y reportar.
+//
+// Ninguna función exportada modifica los slices de órdenes o certificados que
+// recibe: las que necesitan ordenar o mezclar trabajan sobre una copia. Los
+// resultados, en cambio, pueden compartir los slices Orders y los mapas Tags
+// de la entrada, por lo que modificarlos puede afectar a los datos originales.
+package main
@@ -0,0 +1,21 @@
+package main
+
+import "errors"
+
+// Categorías de error que devuelven la verificación y la carga de certificados.
+// Los errores concretos las envuelven con el detalle del caso, por lo que deben
+// compararse con errors.Is.
+var (
+	// ErrOrderExceedsLimit indica una orden cuyo monto supera por sí solo el límite
+	ErrOrderExceedsLimit = errors.New("excede el límite por sí misma")
+
+	// ErrCertificateOverflow indica un certificado cuyo monto supera el límite
+	ErrCertificateOverflow = errors.New("excede el límite")
+
+	// ErrDuplicateOrderID indica una orden asignada a más de un certificado
+	ErrDuplicateOrderID = errors.New("orden duplicada")
+
+	// ErrConservationFailed indica que los certificados no contienen exactamente
+	// las órdenes de entrada o que sus montos no coinciden con ellas
+	ErrConservationFailed = errors.New("las órdenes no se conservan")
+)
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExportOptions configura la escritura de certificados
+type ExportOptions struct {
+	// IDFormat, si no es nil, convierte el ID de cada certificado en el
+	// identificador que espera el sistema externo, como "CERT-0001". En JSON
+	// el ID pasa a ser un string, que LoadCertificatesJSON no lee. Una
+	// asignación CSV con IDs formateados se recarga con
+	// LoadCertificatesFromAssignmentWithOptions y la función inversa en
+	// LoadOptions.ParseCertificateID. nil = el número tal cual, como con "%d".
+	IDFormat func(int) string
+}
+
+// formatID devuelve el identificador exportado del certificado id
+func (opts *ExportOptions) formatID(id int) string {
+	if opts.IDFormat == nil {
+		return strconv.Itoa(id)
+	}
+	return opts.IDFormat(id)
+}
+
+// exportedCertificate es un certificado con el ID ya formateado; el campo ID
+// reemplaza en JSON al del certificado embebido
+type exportedCertificate struct {
+	ID string
+	Certificate
+}
+
+// jsonCertificate devuelve el valor que se codifica en JSON para cert
+func (opts *ExportOptions) jsonCertificate(cert Certificate) any {
+	if opts.IDFormat == nil {
+		return cert
+	}
+	return exportedCertificate{ID: opts.IDFormat(cert.ID), Certificate: cert}
+}
+
+// FilterCertificates devuelve, en un slice nuevo y conservando el orden, los
+// certificados para los que keep devuelve true, por ejemplo para exportar solo
+// una parte con los Write*. FillBelow y ContainsMerchant son predicados
+// habituales para keep.
+func FilterCertificates(certs []Certificate, keep func(Certificate) bool) []Certificate {
+	var kept []Certificate
+	for _, cert := range certs {
+		if keep(cert) {
+			kept = append(kept, cert)
+		}
+	}
+	return kept
+}
+
+// FillBelow devuelve un predicado para FilterCertificates que conserva los
+// certificados cuyo llenado (Amount/limit, o sobre su propio Limit si lo tiene)
+// es menor que threshold; 0.5 conserva los llenos por debajo de la mitad
+func FillBelow(limit, threshold float64) func(Certificate) bool {
+	return func(cert Certificate) bool {
+		certLimit := limit
+		if cert.Limit > 0 {
+			certLimit = cert.Limit
+		}
+		return cert.Amount/certLimit < threshold
+	}
+}
+
+// ContainsMerchant devuelve un predicado para FilterCertificates que conserva
+// los certificados con al menos una orden del comerciante indicado
+func ContainsMerchant(merchantID int) func(Certificate) bool {
+	return func(cert Certificate) bool {
+		for _, order := range cert.Orders {
+			if order.MerchantID == merchantID {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WriteCertificatesNDJSON escribe un certificado por línea en formato JSON Lines.
+// Cada línea es un objeto independiente, por lo que la salida puede procesarse
+// de forma incremental sin cargar el conjunto completo.
+func WriteCertificatesNDJSON(w io.Writer, certs []Certificate) error {
+	return WriteCertificatesNDJSONWithOptions(w, certs, ExportOptions{})
+}
+
+// WriteCertificatesNDJSONWithOptions es como WriteCertificatesNDJSON pero con
+// las opciones indicadas
+func WriteCertificatesNDJSONWithOptions(w io.Writer, certs []Certificate, opts ExportOptions) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, cert := range certs {
+		// Encode agrega el salto de línea después de cada objeto
+		if err := enc.Encode(opts.jsonCertificate(cert)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteCertificatesJSON escribe los certificados como un único arreglo JSON
+func WriteCertificatesJSON(w io.Writer, certs []Certificate) error {
+	return WriteCertificatesJSONWithOptions(w, certs, ExportOptions{})
+}
+
+// WriteCertificatesJSONWithOptions es como WriteCertificatesJSON pero con las
+// opciones indicadas
+func WriteCertificatesJSONWithOptions(w io.Writer, certs []Certificate, opts ExportOptions) error {
+	var value any = certs
+	if opts.IDFormat != nil {
+		exported := make([]any, len(certs))
+		for i, cert := range certs {
+			exported[i] = opts.jsonCertificate(cert)
+		}
+		value = exported
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := json.NewEncoder(bw).Encode(value); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteOrdersCSV escribe las órdenes en el formato CSV que lee LoadOrdersCSV.
+// Si alguna orden tiene Timestamp se agrega la columna timestamp, y cada clave
+// de Tags presente en alguna orden se escribe como una columna adicional, en
+// orden alfabético.
+func WriteOrdersCSV(w io.Writer, orders []Order) error {
+	tagSet := make(map[string]bool)
+	hasTimestamp := false
+	for _, order := range orders {
+		if !order.Timestamp.IsZero() {
+			hasTimestamp = true
+		}
+		for key := range order.Tags {
+			tagSet[key] = true
+		}
+	}
+	tagNames := make([]string, 0, len(tagSet))
+	for key := range tagSet {
+		tagNames = append(tagNames, key)
+	}
+	sort.Strings(tagNames)
+
+	cw := csv.NewWriter(w)
+	header := []string{"id", "amount", "merchant_id"}
+	if hasTimestamp {
+		header = append(header, timestampColumn)
+	}
+	header = append(header, tagNames...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		record := []string{
+			strconv.Itoa(order.ID),
+			strconv.FormatFloat(order.Amount, 'f', -1, 64),
+			strconv.Itoa(order.MerchantID),
+		}
+		if hasTimestamp {
+			ts := ""
+			if !order.Timestamp.IsZero() {
+				ts = order.Timestamp.Format(time.RFC3339Nano)
+			}
+			record = append(record, ts)
+		}
+		for _, key := range tagNames {
+			record = append(record, order.Tags[key])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteAssignmentCSV escribe la asignación de cada orden a su certificado, con
+// columnas order_id y certificate_id, en el orden de los certificados. Es el
+// formato que lee LoadCertificatesFromAssignment.
+func WriteAssignmentCSV(w io.Writer, certs []Certificate) error {
+	return WriteAssignmentCSVWithOptions(w, certs, ExportOptions{})
+}
+
+// WriteAssignmentCSVWithOptions es como WriteAssignmentCSV pero con las
+// opciones indicadas
+func WriteAssignmentCSVWithOptions(w io.Writer, certs []Certificate, opts ExportOptions) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"order_id", "certificate_id"}); err != nil {
+		return err
+	}
+	for _, cert := range certs {
+		certID := opts.formatID(cert.ID)
+		for _, order := range cert.Orders {
+			if err := cw.Write([]string{strconv.Itoa(order.ID), certID}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// AssignmentEntry es un valor no nulo de la matriz de asignación: la fila es la
+// orden, la columna el certificado y el valor el monto de la orden
+type AssignmentEntry struct {
+	OrderID int
+	CertID  int
+	Amount  float64
+}
+
+// AssignmentMatrix devuelve la asignación de órdenes a certificados como una
+// matriz dispersa, con una entrada por orden colocada en el orden de los
+// certificados, para herramientas de análisis que trabajan con grafos
+// bipartitos o matrices
+func AssignmentMatrix(certs []Certificate) []AssignmentEntry {
+	total := 0
+	for _, cert := range certs {
+		total += len(cert.Orders)
+	}
+	entries := make([]AssignmentEntry, 0, total)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			entries = append(entries, AssignmentEntry{OrderID: order.ID, CertID: cert.ID, Amount: order.Amount})
+		}
+	}
+	return entries
+}
+
+// WritePerMerchantReports escribe en dir un archivo merchant_<id>.csv por
+// comerciante con sus órdenes certificadas: una fila por orden con el
+// certificado que la contiene y el monto total de ese certificado, en el orden
+// de certs. Crea dir si no existe.
+func WritePerMerchantReports(dir string, certs []Certificate) error {
+	rows := make(map[int][][]string)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			rows[order.MerchantID] = append(rows[order.MerchantID], []string{
+				strconv.Itoa(cert.ID),
+				strconv.FormatFloat(cert.Amount, 'f', -1, 64),
+				strconv.Itoa(order.ID),
+				strconv.FormatFloat(order.Amount, 'f', -1, 64),
+			})
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for merchantID, merchantRows := range rows {
+		path := filepath.Join(dir, fmt.Sprintf("merchant_%d.csv", merchantID))
+		if err := writeMerchantReport(path, merchantRows); err != nil {
+			return fmt.Errorf("comerciante %d: %w", merchantID, err)
+		}
+	}
+	return nil
+}
+
+// writeMerchantReport escribe el archivo de un comerciante de WritePerMerchantReports
+func writeMerchantReport(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"certificate_id", "certificate_amount", "order_id", "amount"})
+	cw.WriteAll(rows)
+	if err := cw.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
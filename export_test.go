@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteCertificatesNDJSONOneObjectPerLine(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 150.5, Orders: []Order{{ID: 1, Amount: 100, MerchantID: 1}, {ID: 2, Amount: 50.5, MerchantID: 2}}},
+		{ID: 2, Amount: 75, Orders: []Order{{ID: 3, Amount: 75, MerchantID: 1}}},
+		{ID: 3, Amount: 0}, // Vacío: también ocupa su línea
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCertificatesNDJSON(&buf, certs); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		var cert Certificate
+		if err := json.Unmarshal(scanner.Bytes(), &cert); err != nil {
+			t.Fatalf("line %d does not unmarshal on its own: %v", lines+1, err)
+		}
+		if cert.ID != certs[lines].ID || cert.Amount != certs[lines].Amount || len(cert.Orders) != len(certs[lines].Orders) {
+			t.Errorf("line %d = %+v, want %+v", lines+1, cert, certs[lines])
+		}
+		lines++
+	}
+	if lines != len(certs) {
+		t.Errorf("wrote %d lines, want %d", lines, len(certs))
+	}
+}
+
+func TestWritePerMerchantReportsOneFilePerMerchant(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 400, MerchantID: 7},
+		{ID: 2, Amount: 350.25, MerchantID: 3},
+		{ID: 3, Amount: 500, MerchantID: 7},
+		{ID: 4, Amount: 200, MerchantID: 9},
+		{ID: 5, Amount: 120, MerchantID: 3},
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: 1000})
+	dir := filepath.Join(t.TempDir(), "reportes") // Todavía no existe
+
+	if err := WritePerMerchantReports(dir, result.Certificates); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("got %d files, want one per merchant (3)", len(entries))
+	}
+	certOf := make(map[int]Certificate)
+	for _, cert := range result.Certificates {
+		for _, order := range cert.Orders {
+			certOf[order.ID] = cert
+		}
+	}
+	for merchantID, orderIDs := range map[int][]int{7: {1, 3}, 3: {2, 5}, 9: {4}} {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("merchant_%d.csv", merchantID)))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		rows, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != len(orderIDs)+1 {
+			t.Errorf("merchant %d: got %d rows, want header plus %d", merchantID, len(rows), len(orderIDs))
+			continue
+		}
+		got := make(map[int][]string)
+		for _, row := range rows[1:] {
+			id, _ := strconv.Atoi(row[2])
+			got[id] = row
+		}
+		for _, id := range orderIDs {
+			order, cert := orders[id-1], certOf[id]
+			want := []string{
+				strconv.Itoa(cert.ID),
+				strconv.FormatFloat(cert.Amount, 'f', -1, 64),
+				strconv.Itoa(id),
+				strconv.FormatFloat(order.Amount, 'f', -1, 64),
+			}
+			if !slices.Equal(got[id], want) {
+				t.Errorf("merchant %d, order %d: row %v, want %v", merchantID, id, got[id], want)
+			}
+		}
+	}
+}
+
+func TestAssignmentMatrixOneEntryPerPlacedOrder(t *testing.T) {
+	var orders []Order
+	for i := range 75 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(40 + i*29%160), MerchantID: i%6 + 1})
+	}
+	// La de 5000 no se coloca y no debe aparecer
+	orders = append(orders, Order{ID: 76, Amount: 5000, MerchantID: 1})
+	result := GenerateCertificates(orders, PackOptions{Limit: 800})
+
+	entries := AssignmentMatrix(result.Certificates)
+	placed := 0
+	for _, cert := range result.Certificates {
+		placed += len(cert.Orders)
+	}
+	if len(entries) != placed || placed != 75 {
+		t.Fatalf("got %d entries for %d placed orders, want 75 of each", len(entries), placed)
+	}
+
+	// Cada entrada apunta al certificado que contiene la orden, con su monto
+	certOf := make(map[int]int)
+	for _, cert := range result.Certificates {
+		for _, order := range cert.Orders {
+			certOf[order.ID] = cert.ID
+		}
+	}
+	for _, entry := range entries {
+		if entry.CertID != certOf[entry.OrderID] || entry.Amount != orders[entry.OrderID-1].Amount {
+			t.Errorf("entry %+v, want certificate %d and amount %v",
+				entry, certOf[entry.OrderID], orders[entry.OrderID-1].Amount)
+		}
+	}
+}
+
+func TestIDFormatInExports(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 600, MerchantID: 1},
+		{ID: 2, Amount: 300, MerchantID: 2},
+		{ID: 3, Amount: 700, MerchantID: 1},
+	}
+	certs := []Certificate{
+		{ID: 1, Amount: 900, Orders: orders[:2]},
+		{ID: 12, Amount: 700, Orders: orders[2:]},
+	}
+	formatted := ExportOptions{IDFormat: func(id int) string { return fmt.Sprintf("CERT-%04d", id) }}
+
+	for _, tt := range []struct {
+		opts ExportOptions
+		ids  []string
+	}{
+		{ExportOptions{}, []string{"1", "12"}},
+		{formatted, []string{`"CERT-0001"`, `"CERT-0012"`}},
+	} {
+		var js, nd bytes.Buffer
+		if err := WriteCertificatesJSONWithOptions(&js, certs, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteCertificatesNDJSONWithOptions(&nd, certs, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		var fromJSON []struct{ ID json.RawMessage }
+		if err := json.Unmarshal(js.Bytes(), &fromJSON); err != nil {
+			t.Fatal(err)
+		}
+		var fromNDJSON []struct{ ID json.RawMessage }
+		dec := json.NewDecoder(&nd)
+		for dec.More() {
+			var cert struct{ ID json.RawMessage }
+			if err := dec.Decode(&cert); err != nil {
+				t.Fatal(err)
+			}
+			fromNDJSON = append(fromNDJSON, cert)
+		}
+		for name, decoded := range map[string][]struct{ ID json.RawMessage }{"JSON": fromJSON, "NDJSON": fromNDJSON} {
+			if len(decoded) != len(tt.ids) {
+				t.Fatalf("%s: got %d certificates, want %d", name, len(decoded), len(tt.ids))
+			}
+			for i, id := range tt.ids {
+				if string(decoded[i].ID) != id {
+					t.Errorf("%s: certificate %d has ID %s, want %s", name, i, decoded[i].ID, id)
+				}
+			}
+		}
+
+		var assignment bytes.Buffer
+		if err := WriteAssignmentCSVWithOptions(&assignment, certs, tt.opts); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&assignment).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		unquote := func(id string) string { return strings.Trim(id, `"`) }
+		want := [][]string{{"order_id", "certificate_id"},
+			{"1", unquote(tt.ids[0])}, {"2", unquote(tt.ids[0])}, {"3", unquote(tt.ids[1])}}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("assignment rows = %v, want %v", rows, want)
+		}
+	}
+}
+
+func TestFilterCertificatesUnderHalfFull(t *testing.T) {
+	const limit = 1000
+	certs := []Certificate{
+		{ID: 1, Amount: 980, Orders: []Order{{ID: 1, Amount: 980, MerchantID: 1}}},
+		{ID: 2, Amount: 120, Orders: []Order{{ID: 2, Amount: 120, MerchantID: 2}}},
+		{ID: 3, Amount: 500, Orders: []Order{{ID: 3, Amount: 500, MerchantID: 3}}}, // Justo a la mitad: no está por debajo
+		{ID: 4, Amount: 499.99, Orders: []Order{{ID: 4, Amount: 499.99, MerchantID: 2}}},
+		// Con límite propio de $3,000 está al 30% aunque pase la mitad de limit
+		{ID: 5, Amount: 900, Limit: 3000, Orders: []Order{{ID: 5, Amount: 900, MerchantID: 4}}},
+	}
+	before := cloneCertificates(certs)
+
+	ids := func(certs []Certificate) []int {
+		var ids []int
+		for _, cert := range certs {
+			ids = append(ids, cert.ID)
+		}
+		return ids
+	}
+	if got := ids(FilterCertificates(certs, FillBelow(limit, 0.5))); !slices.Equal(got, []int{2, 4, 5}) {
+		t.Errorf("FillBelow(0.5) kept %v, want [2 4 5]", got)
+	}
+	if got := ids(FilterCertificates(certs, ContainsMerchant(2))); !slices.Equal(got, []int{2, 4}) {
+		t.Errorf("ContainsMerchant(2) kept %v, want [2 4]", got)
+	}
+	if got := FilterCertificates(certs, ContainsMerchant(99)); len(got) != 0 {
+		t.Errorf("ContainsMerchant(99) kept %v, want none", ids(got))
+	}
+	if !reflect.DeepEqual(certs, before) {
+		t.Error("FilterCertificates modified its input")
+	}
+}
+
+func TestIDFormatAssignmentReload(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 600, MerchantID: 1},
+		{ID: 2, Amount: 300, MerchantID: 2},
+		{ID: 3, Amount: 700, MerchantID: 1},
+	}
+	certs := []Certificate{
+		{ID: 1, Amount: 900, Orders: orders[:2]},
+		{ID: 12, Amount: 700, Orders: orders[2:]},
+	}
+	opts := ExportOptions{IDFormat: func(id int) string { return fmt.Sprintf("CERT-%04d", id) }}
+	parse := func(s string) (int, error) {
+		var id int
+		if _, err := fmt.Sscanf(s, "CERT-%d", &id); err != nil {
+			return 0, fmt.Errorf("ID de certificado %q: %w", s, err)
+		}
+		return id, nil
+	}
+
+	var assignment bytes.Buffer
+	if err := WriteAssignmentCSVWithOptions(&assignment, certs, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Los IDs formateados no se leen como números: hace falta la inversa
+	if _, err := LoadCertificatesFromAssignment(orders, bytes.NewReader(assignment.Bytes())); err == nil {
+		t.Error("LoadCertificatesFromAssignment accepted CERT-0001 as a certificate ID")
+	}
+	reloaded, err := LoadCertificatesFromAssignmentWithOptions(orders, bytes.NewReader(assignment.Bytes()),
+		LoadOptions{ParseCertificateID: parse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded) != 2 || reloaded[0].ID != 1 || reloaded[1].ID != 12 ||
+		!CertificatesEqual(reloaded[0], certs[0]) || !CertificatesEqual(reloaded[1], certs[1]) {
+		t.Errorf("reloaded %+v, want the original certificates", reloaded)
+	}
+}
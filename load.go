@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampColumn es el encabezado de la columna CSV que corresponde a Order.Timestamp
+const timestampColumn = "timestamp"
+
+// LoadOrdersCSV lee órdenes en formato CSV con las columnas id, amount y
+// merchant_id. Si la primera fila no es numérica se toma como encabezado; en ese
+// caso una columna adicional "timestamp" (RFC 3339) se carga en Timestamp y las
+// demás como Tags usando el nombre de la columna como clave. Los errores indican
+// la línea del archivo donde ocurrieron.
+func LoadOrdersCSV(r io.Reader) ([]Order, error) {
+	return LoadOrdersCSVWithOptions(r, LoadOptions{})
+}
+
+// LoadOptions configura la lectura de órdenes en CSV
+type LoadOptions struct {
+	// UseCents lee los montos como centavos enteros directamente del texto (ver
+	// ParseCents) en lugar de pasar por strconv.ParseFloat, y rechaza los montos
+	// con más de dos decimales en vez de redondearlos en silencio.
+	UseCents bool
+
+	// StrictCents rechaza los montos que no equivalen a una cantidad exacta de
+	// centavos, como "10.123", que el sistema de emisión no puede procesar. A
+	// diferencia de UseCents mantiene la lectura con strconv.ParseFloat, así que
+	// acepta "10.120" o "1e2"; con UseCents no hace falta.
+	StrictCents bool
+
+	// ParseCertificateID, si no es nil, convierte los IDs de certificado de una
+	// asignación (ver LoadCertificatesFromAssignmentWithOptions) en números. Es
+	// la inversa de ExportOptions.IDFormat y hace falta para recargar una
+	// asignación exportada con IDs como "CERT-0001". nil = strconv.Atoi.
+	ParseCertificateID func(string) (int, error)
+}
+
+// LoadOrdersCSVWithOptions es como LoadOrdersCSV pero con las opciones indicadas
+func LoadOrdersCSVWithOptions(r io.Reader, opts LoadOptions) ([]Order, error) {
+	// Algunos sistemas de los socios agregan un BOM UTF-8 al inicio del archivo;
+	// sin quitarlo, una primera fila de datos se confundiría con el encabezado
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = 0 // Todas las filas con la misma cantidad de columnas que la primera
+	reader.TrimLeadingSpace = true
+
+	var orders []Order
+	var tagNames []string
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) < 3 {
+			return nil, fmt.Errorf("línea %d: se esperaban al menos 3 columnas, hay %d", line, len(record))
+		}
+
+		if first {
+			first = false
+			if _, err := strconv.Atoi(strings.TrimSpace(record[0])); err != nil {
+				tagNames = record[3:] // Encabezado
+				continue
+			}
+			if len(record) > 3 {
+				return nil, fmt.Errorf("línea %d: las columnas adicionales requieren un encabezado", line)
+			}
+		}
+
+		order, err := parseOrderRecord(record, &opts)
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %w", line, err)
+		}
+		for i, name := range tagNames {
+			value := record[3+i]
+			if name == timestampColumn {
+				if value == "" {
+					continue
+				}
+				ts, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+				if err != nil {
+					return nil, fmt.Errorf("línea %d: timestamp inválido %q", line, value)
+				}
+				order.Timestamp = ts
+				continue
+			}
+			if value != "" {
+				if order.Tags == nil {
+					order.Tags = make(map[string]string, len(tagNames))
+				}
+				order.Tags[name] = value
+			}
+		}
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// parseOrderRecord convierte una fila id,amount,merchant_id en una orden
+func parseOrderRecord(record []string, opts *LoadOptions) (Order, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(record[0]))
+	if err != nil {
+		return Order{}, fmt.Errorf("ID de orden inválido %q", record[0])
+	}
+	var amount float64
+	if opts.UseCents {
+		cents, err := ParseCents(record[1])
+		if err != nil {
+			return Order{}, err
+		}
+		// El cociente es el float64 más cercano al monto decimal exacto
+		amount = float64(cents) / 100
+	} else {
+		amount, err = strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return Order{}, fmt.Errorf("monto inválido %q", record[1])
+		}
+		if opts.StrictCents && math.Round(amount*100)/100 != amount {
+			return Order{}, fmt.Errorf("monto %q con más de dos decimales", record[1])
+		}
+	}
+	merchantID, err := strconv.Atoi(strings.TrimSpace(record[2]))
+	if err != nil {
+		return Order{}, fmt.Errorf("ID de comerciante inválido %q", record[2])
+	}
+
+	order := Order{ID: id, Amount: amount, MerchantID: merchantID}
+	if err := ValidateOrders([]Order{order}); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// ParseCents convierte un monto decimal como "499999.99" o "-0.1" en centavos
+// enteros sin pasar por punto flotante, por lo que "0.10" da exactamente 10.
+// Acepta hasta dos decimales y rechaza exponentes y montos fuera de rango.
+func ParseCents(s string) (int64, error) {
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	if negative || strings.HasPrefix(text, "+") {
+		text = text[1:]
+	}
+	whole, frac, _ := strings.Cut(text, ".")
+	if (whole == "" && frac == "") || len(frac) > 2 {
+		return 0, fmt.Errorf("monto inválido %q", s)
+	}
+	for _, part := range []string{whole, frac} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("monto inválido %q", s)
+			}
+		}
+	}
+
+	var units int64
+	if whole != "" {
+		var err error
+		units, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || units > math.MaxInt64/100-1 {
+			return 0, fmt.Errorf("monto fuera de rango %q", s)
+		}
+	}
+	cents := units * 100
+	if frac != "" {
+		f, _ := strconv.ParseInt(frac, 10, 64)
+		if len(frac) == 1 {
+			f *= 10
+		}
+		cents += f
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// LoadCertificatesJSON lee un arreglo JSON de certificados como el que produce
+// WriteCertificatesJSON
+func LoadCertificatesJSON(r io.Reader) ([]Certificate, error) {
+	var certs []Certificate
+	if err := json.NewDecoder(r).Decode(&certs); err != nil {
+		return nil, fmt.Errorf("certificados JSON inválidos: %w", err)
+	}
+	return certs, nil
+}
+
+// LoadCertificatesFromAssignment reconstruye los certificados a partir de un CSV
+// order_id,certificate_id como el que escribe WriteAssignmentCSV, sin volver a
+// ejecutar el empaquetado. Las órdenes se buscan por ID en orders; dentro de cada
+// certificado conservan el orden del archivo y el monto se recalcula. Los
+// certificados se devuelven ordenados por ID. IsOverflow no se conserva en la
+// asignación, por lo que queda en false. Los IDs de certificado deben ser
+// números; para los formateados con ExportOptions.IDFormat está
+// LoadCertificatesFromAssignmentWithOptions.
+func LoadCertificatesFromAssignment(orders []Order, r io.Reader) ([]Certificate, error) {
+	return LoadCertificatesFromAssignmentWithOptions(orders, r, LoadOptions{})
+}
+
+// LoadCertificatesFromAssignmentWithOptions es como
+// LoadCertificatesFromAssignment pero lee los IDs de certificado con
+// opts.ParseCertificateID. Las demás opciones no se aplican.
+func LoadCertificatesFromAssignmentWithOptions(orders []Order, r io.Reader, opts LoadOptions) ([]Certificate, error) {
+	parseCertID := opts.ParseCertificateID
+	if parseCertID == nil {
+		parseCertID = strconv.Atoi
+	}
+	byID := make(map[int]Order, len(orders))
+	for _, order := range orders {
+		byID[order.ID] = order
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	index := make(map[int]int) // ID de certificado -> posición en certs
+	assigned := make(map[int]bool)
+	var certs []Certificate
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		orderID, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			if first {
+				first = false
+				continue // Encabezado
+			}
+			return nil, fmt.Errorf("línea %d: ID de orden inválido %q", line, record[0])
+		}
+		first = false
+		certID, err := parseCertID(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: ID de certificado inválido %q", line, record[1])
+		}
+
+		order, ok := byID[orderID]
+		if !ok {
+			return nil, fmt.Errorf("línea %d: %w: orden %d desconocida", line, ErrConservationFailed, orderID)
+		}
+		if assigned[orderID] {
+			return nil, fmt.Errorf("línea %d: %w: orden %d asignada más de una vez", line, ErrDuplicateOrderID, orderID)
+		}
+		assigned[orderID] = true
+
+		i, ok := index[certID]
+		if !ok {
+			i = len(certs)
+			index[certID] = i
+			certs = append(certs, Certificate{ID: certID})
+		}
+		certs[i].Orders = append(certs[i].Orders, order)
+		certs[i].Amount += order.Amount
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].ID < certs[j].ID
+	})
+	return certs, nil
+}
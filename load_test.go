@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func FuzzLoadOrdersCSV(f *testing.F) {
+	seeds := []string{
+		// Válidos
+		"1,100.50,1\n2,20,2\n",
+		"id,amount,merchant_id\n1,100.50,1\n",
+		"id,amount,merchant_id,timestamp,region\n1,10,1,2024-05-01T10:00:00Z,norte\n2,20,2,,\n",
+		"\ufeff1,100.50,1\n",
+		"\ufeffid,amount,merchant_id\n1,9.99,3\n",
+		// Malformados
+		"",
+		"1,abc,1\n",
+		"1,100\n",
+		"id,amount,merchant_id\n1,NaN,1\n",
+		"1,-5,1\n",
+		"\"1,100,1\n",
+		"1,1e400,1\n",
+		"id,amount,merchant_id,timestamp\n1,10,1,ayer\n",
+		// Filas con distinta cantidad de columnas
+		"1,100,1\n2,200,2,extra\n",
+		"id,amount,merchant_id,region\n1,10,1\n",
+		"1,100,1,x\n",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range []LoadOptions{{}, {UseCents: true}, {StrictCents: true}} {
+			orders, err := LoadOrdersCSVWithOptions(bytes.NewReader(data), opts)
+			if err != nil && orders != nil {
+				t.Fatalf("%+v: returned %d orders together with error %v", opts, len(orders), err)
+			}
+		}
+	})
+}
+
+func TestLoadCertificatesFromAssignmentRoundTrip(t *testing.T) {
+	var orders []Order
+	for i := range 40 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(50 + i*13%400), MerchantID: i%6 + 1})
+	}
+	packed := GenerateCertificates(orders, PackOptions{Limit: 1000}).Certificates
+
+	var buf bytes.Buffer
+	if err := WriteAssignmentCSV(&buf, packed); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := LoadCertificatesFromAssignment(orders, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reloaded) != len(packed) {
+		t.Fatalf("reloaded %d certificates, want %d", len(reloaded), len(packed))
+	}
+	if DigestCertificates(reloaded) != DigestCertificates(packed) {
+		t.Errorf("reloaded certificates %+v, want %+v", reloaded, packed)
+	}
+}
+
+func TestParseCentsExact(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want int64
+	}{
+		{"0.10", 10},
+		{"0.1", 10},
+		{"499999.99", 49999999},
+		{" 12 ", 1200},
+		{".05", 5},
+		{"-0.1", -10},
+	} {
+		got, err := ParseCents(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCents(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", ".", "10.123", "1e2", "1,5", "99999999999999999999"} {
+		if got, err := ParseCents(in); err == nil {
+			t.Errorf("ParseCents(%q) = %d, want an error", in, got)
+		}
+	}
+}
+
+func TestLoadOrdersCSVUseCents(t *testing.T) {
+	csv := "1,0.10,1\n2,499999.99,2\n"
+	orders, err := LoadOrdersCSVWithOptions(strings.NewReader(csv), LoadOptions{UseCents: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Los montos son el float64 más cercano a los centavos exactos
+	if orders[0].Amount != float64(10)/100 || orders[1].Amount != float64(49999999)/100 {
+		t.Errorf("amounts = %v, %v; want 0.10 and 499999.99", orders[0].Amount, orders[1].Amount)
+	}
+
+	// Con UseCents un tercer decimal es un error en vez de redondearse en silencio
+	if _, err := LoadOrdersCSVWithOptions(strings.NewReader("1,10.123,1\n"), LoadOptions{UseCents: true}); err == nil {
+		t.Error("UseCents accepted an amount with three decimals")
+	}
+}
+
+func TestLoadOrdersCSVStrictCentsNamesLine(t *testing.T) {
+	csv := "id,amount,merchant_id\n" +
+		"1,10.12,1\n" +
+		"2,10.120,2\n" + // Ceros de más: sigue siendo una cantidad exacta de centavos
+		"3,10.123,1\n"
+	_, err := LoadOrdersCSVWithOptions(strings.NewReader(csv), LoadOptions{StrictCents: true})
+	if err == nil {
+		t.Fatal("StrictCents accepted 10.123")
+	}
+	if !strings.Contains(err.Error(), "línea 4") || !strings.Contains(err.Error(), "10.123") {
+		t.Errorf("error %q does not name line 4 and the amount", err)
+	}
+
+	// Sin la opción se conserva el comportamiento de siempre
+	orders, err := LoadOrdersCSV(strings.NewReader(csv))
+	if err != nil || len(orders) != 3 || orders[2].Amount != 10.123 {
+		t.Errorf("without StrictCents: %+v, %v; want the three orders as read", orders, err)
+	}
+	if _, err := LoadOrdersCSVWithOptions(strings.NewReader(csv[:strings.Index(csv, "3,")]), LoadOptions{StrictCents: true}); err != nil {
+		t.Errorf("StrictCents rejected amounts with at most two significant decimals: %v", err)
+	}
+}
+
+func TestLoadOrdersCSVRejectsInvalidMerchant(t *testing.T) {
+	csv := "id,amount,merchant_id\n" +
+		"1,10,1\n" +
+		"2,20,0\n"
+	_, err := LoadOrdersCSV(strings.NewReader(csv))
+	if err == nil {
+		t.Fatal("LoadOrdersCSV accepted merchant 0")
+	}
+	if !strings.Contains(err.Error(), "línea 3") || !strings.Contains(err.Error(), "orden 2") {
+		t.Errorf("error %q does not name line 3 and order 2", err)
+	}
+
+	// Las órdenes construidas en memoria se validan al empaquetar
+	orders := []Order{{ID: 1, Amount: 10, MerchantID: 1}, {ID: 2, Amount: 20, MerchantID: -1}}
+	if _, err := GenerateCertificatesContext(context.Background(), orders, PackOptions{}); err == nil {
+		t.Error("GenerateCertificatesContext accepted merchant -1")
+	}
+	if result := GenerateCertificates(orders, PackOptions{}); len(result.Certificates) != 0 {
+		t.Errorf("GenerateCertificates packed %d certificates from invalid orders; want none", len(result.Certificates))
+	}
+}
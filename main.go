@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
-	"sort"
+	"os"
+	"os/signal"
 	"time"
 )
 
@@ -12,34 +17,122 @@ type Order struct {
 	ID         int
 	Amount     float64
 	MerchantID int
+	Tags       map[string]string `json:",omitempty"` // Metadatos libres (región, canal, ...)
+	Timestamp  time.Time         `json:",omitzero"`  // Momento de la orden (cero = desconocido)
 }
 
 type Certificate struct {
-	ID     int
-	Amount float64
-	Orders []Order
+	ID         int
+	Amount     float64
+	Orders     []Order
+	IsOverflow bool      // Emitido con OverflowLimit como excepción al límite normal
+	Limit      float64   `json:",omitempty"` // Límite propio del tramo (0 = el límite general)
+	CreatedAt  time.Time `json:",omitzero"`  // Momento en que se cerró (ver PackOptions.Now)
+}
+
+// GenOptions configura la generación de órdenes
+type GenOptions struct {
+	Merchants         int   // Cantidad de comerciantes
+	OrdersPerMerchant int   // Órdenes generadas para cada comerciante
+	Seed              int64 // Semilla del generador (0 = basada en la hora actual)
+	ProgressEvery     int   // Comerciantes entre cada reporte de progreso (0 = sin reportes)
+	MaxTotalOrders    int   // Tope de órdenes en total; los últimos comerciantes reciben menos o ninguna (0 = sin tope)
+
+	// MerchantProfiles asigna a cada comerciante un monto medio al azar, elegido
+	// una sola vez en el rango habitual, y genera sus órdenes agrupadas alrededor
+	// de ese monto en lugar de uniformes, como el perfil de venta de un comercio
+	// real. Sirve para probar las estrategias de cohesión por comerciante.
+	MerchantProfiles bool
+
+	// OnProgress recibe cada reporte de progreso; si es nil se imprime en consola
+	OnProgress func(merchantsDone, totalMerchants, ordersDone int)
 }
 
-// generateOrders genera 612 órdenes para cada uno de los 3500 comerciantes
-func generateOrders() ([]Order, error) {
-	const numMerchants = 3500
-	const ordersPerMerchant = 612
-	totalOrders := numMerchants * ordersPerMerchant
+// DefaultGenOptions devuelve la configuración original: 612 órdenes para cada uno
+// de los 3500 comerciantes, reportando progreso cada 100 comerciantes
+func DefaultGenOptions() GenOptions {
+	return GenOptions{
+		Merchants:         3500,
+		OrdersPerMerchant: 612,
+		ProgressEvery:     100,
+	}
+}
+
+// generateOrders genera opts.OrdersPerMerchant órdenes para cada uno de los opts.Merchants comerciantes
+func generateOrders(opts GenOptions) ([]Order, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
 	
 	// Pre-asignar memoria para todas las órdenes mejora significativamente el rendimiento
-	orders := make([]Order, 0, totalOrders)
+	return fillOrders(make([]Order, 0, opts.totalOrders()), opts), nil
+}
+
+// validate rechaza cantidades negativas en opts
+func (opts GenOptions) validate() error {
+	if opts.Merchants < 0 || opts.OrdersPerMerchant < 0 {
+		return fmt.Errorf("configuración inválida: %d comerciantes, %d órdenes por comerciante",
+			opts.Merchants, opts.OrdersPerMerchant)
+	}
+	if opts.MaxTotalOrders < 0 {
+		return fmt.Errorf("configuración inválida: tope de %d órdenes", opts.MaxTotalOrders)
+	}
+	return nil
+}
+
+// totalOrders devuelve cuántas órdenes genera opts, teniendo en cuenta MaxTotalOrders
+func (opts GenOptions) totalOrders() int {
+	total := opts.Merchants * opts.OrdersPerMerchant
+	if opts.MaxTotalOrders > 0 {
+		total = min(total, opts.MaxTotalOrders)
+	}
+	return total
+}
+
+// profileSpread es el desvío de los montos de un comerciante con
+// MerchantProfiles, como fracción de su monto medio
+const profileSpread = 0.1
+
+// profileAmount devuelve un monto alrededor de mean con distribución normal y
+// desvío profileSpread*mean, recortado al rango habitual de 10.0 a 1000.0
+func profileAmount(r *rand.Rand, mean float64) float64 {
+	return min(max(mean+r.NormFloat64()*mean*profileSpread, 10.0), 1000.0)
+}
+
+// fillOrders genera en buf, reutilizando su capacidad, las órdenes que describe
+// opts, ya validadas
+func fillOrders(buf []Order, opts GenOptions) []Order {
+	numMerchants := opts.Merchants
+	ordersPerMerchant := opts.OrdersPerMerchant
+	orders := buf[:0]
 	
 	// Crear un generador de números aleatorios con semilla para reproducibilidad
-	source := rand.NewSource(time.Now().UnixNano())
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	source := rand.NewSource(seed)
 	r := rand.New(source)
 	
 	orderID := 1
+	totalOrders := opts.totalOrders()
 	
 	// Para cada comerciante, generar sus órdenes
 	for merchantID := 1; merchantID <= numMerchants; merchantID++ {
-		for j := 0; j < ordersPerMerchant; j++ {
+		// Solo se sortea con MerchantProfiles, para no alterar la secuencia de
+		// montos de una semilla sin perfiles
+		var mean float64
+		if opts.MerchantProfiles {
+			mean = 10.0 + r.Float64()*990.0
+		}
+		for j := 0; j < ordersPerMerchant && len(orders) < totalOrders; j++ {
 			// Generar un monto aleatorio entre 10.0 y 1000.0
-			amount := 10.0 + r.Float64()*990.0
+			var amount float64
+			if opts.MerchantProfiles {
+				amount = profileAmount(r, mean)
+			} else {
+				amount = 10.0 + r.Float64()*990.0
+			}
 			
 			// Redondear a 2 decimales
 			amount = float64(int(amount*100)) / 100
@@ -54,33 +147,127 @@ func generateOrders() ([]Order, error) {
 			orderID++
 		}
 		
-		// Mostrar progreso cada 100 comerciantes
-		if merchantID%100 == 0 {
-			fmt.Printf("Generadas %d órdenes para %d de %d comerciantes\n", 
-				merchantID*ordersPerMerchant, merchantID, numMerchants)
+		// Mostrar progreso cada opts.ProgressEvery comerciantes
+		if opts.ProgressEvery > 0 && merchantID%opts.ProgressEvery == 0 {
+			if opts.OnProgress != nil {
+				opts.OnProgress(merchantID, numMerchants, len(orders))
+			} else {
+				fmt.Printf("Generadas %d órdenes para %d de %d comerciantes\n", 
+					len(orders), merchantID, numMerchants)
+			}
+		}
+		
+		// Alcanzado el tope, los comerciantes restantes quedan sin órdenes
+		if len(orders) == totalOrders {
+			break
 		}
 	}
 	
-	return orders, nil
+	return orders
+}
+
+// Estructura para representar un certificado en construcción
+type certificateBuilder struct {
+	Orders    []Order
+	Amount    float64
+	merchants map[int]bool // Comerciantes distintos, solo si opts limita la mezcla
+	orderIDs  map[int]bool // IDs de las órdenes, solo si opts tiene pares incompatibles
+	kahan     bool         // Acumular Amount con suma compensada (opts.KahanSummation)
+	sum       kahanSum     // Suma compensada de los montos, solo si kahan
+}
+
+// newCertificateBuilder crea un certificado vacío que registra la información
+// necesaria para las restricciones activas en opts
+func newCertificateBuilder(opts *PackOptions) certificateBuilder {
+	var b certificateBuilder
+	if opts.MaxMerchantsPerCert > 0 {
+		b.merchants = make(map[int]bool)
+	}
+	if opts.conflicts != nil {
+		b.orderIDs = make(map[int]bool)
+	}
+	b.kahan = opts.KahanSummation
+	return b
 }
 
+// fits indica si la orden cabe en el certificado sin superar maxAmount (el límite
+// más la tolerancia, ver limitWithTolerance) y respetando las restricciones de opts
+func (b *certificateBuilder) fits(order Order, maxAmount float64, opts *PackOptions) bool {
+	// Verificación ESTRICTA: la suma debe ser EXACTAMENTE menor o igual al máximo
+	if b.Amount+order.Amount > maxAmount {
+		return false
+	}
+	return opts.MaxMerchantsPerCert == 0 && opts.conflicts == nil || b.allows(order, opts)
+}
+
+// allows verifica las restricciones del certificado que no dependen del monto
+func (b *certificateBuilder) allows(order Order, opts *PackOptions) bool {
+	if opts.MaxMerchantsPerCert > 0 && !b.merchants[order.MerchantID] &&
+		len(b.merchants) >= opts.MaxMerchantsPerCert {
+		return false
+	}
+	for _, other := range opts.conflicts[order.ID] {
+		if b.orderIDs[other] {
+			return false
+		}
+	}
+	return true
+}
+
+// add agrega la orden al certificado
+func (b *certificateBuilder) add(order Order) {
+	if b.merchants != nil {
+		b.merchants[order.MerchantID] = true
+	}
+	if b.orderIDs != nil {
+		b.orderIDs[order.ID] = true
+	}
+	b.Orders = append(b.Orders, order)
+	if b.kahan {
+		b.sum.add(order.Amount)
+		b.Amount = b.sum.value()
+	} else {
+		b.Amount += order.Amount
+	}
+}
+
+// contextCheckInterval es cada cuántas órdenes el empaquetado revisa si el contexto terminó
+const contextCheckInterval = 1024
+
 // Función para generar certificados basados en un límite de monto
 // Con optimización para llenar al máximo cada certificado, dejando solo los últimos 30 para equilibrarse
-func generateCertificates(orders []Order, limitAmount float64) []Certificate {
-	// Verificación adicional para asegurar que ningún certificado exceda el límite
-	const ABSOLUTE_LIMIT = 500000.0
-	if limitAmount > ABSOLUTE_LIMIT {
-		limitAmount = ABSOLUTE_LIMIT
-	}
-	
-	// Número aproximado de certificados objetivo basado en equilibrio de montos
+// Si ctx termina antes de completar, devuelve los certificados armados hasta ese
+// momento, las órdenes todavía sin procesar y el error del contexto. Si termina
+// durante la fase de equilibrio, el resultado se corta antes de ella: todas sus
+// órdenes quedan pendientes, para que al reanudar se ejecute una sola vez.
+// Con opts.resumeFrom continúa la primera fase sobre esos certificados.
+// Ordena orders en el lugar: los llamadores deben pasarle una copia.
+func generateCertificates(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	limitAmount := opts.Limit
+	done := opts.resumeFrom
+	
+	// Número aproximado de certificados objetivo basado en equilibrio de montos.
+	// Al reanudar se estima sobre la ejecución completa, contando el checkpoint
 	totalAmount := 0.0
+	totalOrders := len(orders)
+	for _, cert := range done {
+		totalAmount += cert.Amount
+		totalOrders += len(cert.Orders)
+	}
 	for _, order := range orders {
 		totalAmount += order.Amount
 	}
 	
 	// Calcular la cantidad estimada de certificados
+	// Monto máximo admitido contando la tolerancia para errores de redondeo
+	maxAmount := limitWithTolerance(limitAmount, &opts)
+	
 	estimatedNumCertificates := int(math.Ceil(totalAmount / limitAmount))
+	// Nunca hacen falta más certificados que órdenes; acotar también protege la
+	// estimación frente a montos negativos o no finitos
+	if estimatedNumCertificates < 0 || estimatedNumCertificates > totalOrders {
+		estimatedNumCertificates = totalOrders
+	}
 	reservedCertificates := 30 // Número de certificados reservados para equilibrio
 	
 	// Si tenemos menos de 30 certificados en total, ajustamos
@@ -91,59 +278,87 @@ func generateCertificates(orders []Order, limitAmount float64) []Certificate {
 		}
 	}
 	
-	// Crear certificados optimizados
-	var certificates []Certificate
+	// Crear certificados optimizados; la estimación evita que el slice crezca varias veces
+	certificates := make([]Certificate, 0, estimatedNumCertificates)
 	certificateID := 1
 	
-	// Primero agrupamos las órdenes por comerciante para mantener cohesión
-	merchantOrders := make(map[int][]Order)
-	for _, order := range orders {
-		merchantOrders[order.MerchantID] = append(merchantOrders[order.MerchantID], order)
-	}
-
-	
 	// Cantidad de órdenes a procesar en la primera fase (certificados maxímamente llenos)
 	numMainCertificates := estimatedNumCertificates - reservedCertificates
 	if numMainCertificates < 1 {
 		numMainCertificates = 1
 	}
+	// El llamador puede fijar el presupuesto de la primera fase en lugar de estimarlo
+	if opts.MainPhaseCertificates > 0 {
+		numMainCertificates = opts.MainPhaseCertificates
+	}
+	// Sin fase de equilibrio, First-Fit-Decreasing abre todos los certificados
+	// que necesite y ninguna orden queda para los de equilibrio
+	if opts.DisableBalancePhase {
+		numMainCertificates = totalOrders
+	}
 	
 	// Implementamos un algoritmo First-Fit-Decreasing para el empaquetado (bin packing)
-	// Primero ordenamos las órdenes por monto de mayor a menor
-	sort.Slice(orders, func(i, j int) bool {
-		return orders[i].Amount > orders[j].Amount
-	})
-	
-	// Estructura para representar un certificado en construcción
-	type CertificateBuilder struct {
-		Orders []Order
-		Amount float64
-	}
+	// Primero ordenamos las órdenes por monto (u opts.SortKey) de mayor a menor
+	sortDescending(orders, &opts)
 	
 	// Crear los certificados para la primera fase (bin packing)
-	certificateBuilders := make([]CertificateBuilder, 0, numMainCertificates)
+	certificateBuilders := make([]certificateBuilder, 0, min(numMainCertificates, estimatedNumCertificates))
 	
 	// Primera fase: Bin Packing con First-Fit-Decreasing
 	var remainingOrders []Order
+	var pendingOrders []Order // Órdenes sin procesar si el contexto termina antes
+	var ctxErr error
+	// Los certificados dedicados a órdenes iguales al límite están llenos y, como
+	// esas órdenes llegan primero, ocupan el comienzo de certificateBuilders
+	fullCertificates := 0
+	
+	// Un checkpoint siempre se corta antes de la fase de equilibrio, así que sus
+	// certificados son todos de la primera fase, en el mismo orden
+	for _, cert := range done {
+		builder := newCertificateBuilder(&opts)
+		for _, order := range cert.Orders {
+			builder.add(order)
+		}
+		certificateBuilders = append(certificateBuilders, builder)
+		if fullCertificates == len(certificateBuilders)-1 && len(cert.Orders) == 1 &&
+			cert.Amount >= limitAmount && cert.Amount <= maxAmount {
+			fullCertificates++
+		}
+	}
 	
 	// Procesar las órdenes más grandes primero
-	for _, order := range orders {
+	for idx, order := range orders {
+		if idx%contextCheckInterval == 0 {
+			if ctxErr = ctx.Err(); ctxErr != nil {
+				pendingOrders = append(append(pendingOrders, orders[idx:]...), remainingOrders...)
+				remainingOrders = nil
+				break
+			}
+		}
+		
 		// Verificar que esta orden no exceda por sí misma el límite
-		if order.Amount > limitAmount {
-			fmt.Printf("ADVERTENCIA: Orden ID %d excede el límite por sí misma: $%.2f\n", 
-				order.ID, order.Amount)
+		if order.Amount > maxAmount {
+			opts.warnf("orden %d excede el límite por sí misma: $%.2f", order.ID, order.Amount)
 			// En este caso, podríamos dividir la orden, pero por ahora solo la reportamos
 			// y la tratamos como cualquier otra orden
 		}
 		
+		// Camino rápido: una orden igual al límite ocupa un certificado propio sin
+		// probar los existentes, y los siguientes no la prueban a ella
+		if order.Amount >= limitAmount && order.Amount <= maxAmount && fullCertificates == len(certificateBuilders) {
+			builder := newCertificateBuilder(&opts)
+			builder.add(order)
+			certificateBuilders = append(certificateBuilders, builder)
+			fullCertificates++
+			continue
+		}
+		
 		placed := false
 		
 		// Intentar colocar la orden en un certificado existente
-		for i := range certificateBuilders {
-			// Verificación ESTRICTA: la suma debe ser EXACTAMENTE menor o igual al límite
-			if certificateBuilders[i].Amount + order.Amount <= limitAmount {
-				certificateBuilders[i].Orders = append(certificateBuilders[i].Orders, order)
-				certificateBuilders[i].Amount += order.Amount
+		for i := fullCertificates; i < len(certificateBuilders); i++ {
+			if certificateBuilders[i].fits(order, maxAmount, &opts) {
+				certificateBuilders[i].add(order)
 				placed = true
 				break
 			}
@@ -153,10 +368,9 @@ func generateCertificates(orders []Order, limitAmount float64) []Certificate {
 		if !placed {
 			// Si tenemos menos certificados que el objetivo, creamos uno nuevo
 			if len(certificateBuilders) < numMainCertificates {
-				certificateBuilders = append(certificateBuilders, CertificateBuilder{
-					Orders: []Order{order},
-					Amount: order.Amount,
-				})
+				builder := newCertificateBuilder(&opts)
+				builder.add(order)
+				certificateBuilders = append(certificateBuilders, builder)
 			} else {
 				// Si ya tenemos suficientes certificados principales, 
 				// esta orden irá a los certificados de equilibrio
@@ -165,217 +379,248 @@ func generateCertificates(orders []Order, limitAmount float64) []Certificate {
 		}
 	}
 	
-	// Convertir los constructores de certificados a certificados reales
-	for _, builder := range certificateBuilders {
-		// Verificación final para asegurar que ningún certificado exceda el límite
-		if builder.Amount > limitAmount {
-			fmt.Printf("ERROR: Certificado ID %d excede el límite: $%.2f\n", 
-				certificateID, builder.Amount)
-			// Esto no debería ocurrir dado nuestro algoritmo, pero verificamos por seguridad
-		}
-		
-		certificates = append(certificates, Certificate{
+	// Convertir los constructores de certificados a certificados reales; los del
+	// checkpoint conservan su ID y su momento de cierre
+	if len(done) > 0 {
+		certificateID = maxCertificateID(done) + 1
+	}
+	for i, builder := range certificateBuilders {
+		// El certificado se queda con el slice del constructor, que no se vuelve a usar
+		cert := Certificate{
 			ID:     certificateID,
 			Amount: builder.Amount,
-			Orders: append([]Order{}, builder.Orders...),
-		})
-		certificateID++
+			Orders: builder.Orders,
+		}
+		if i < len(done) {
+			cert.ID, cert.CreatedAt = done[i].ID, done[i].CreatedAt
+		} else {
+			certificateID++
+		}
+		certificates = append(certificates, cert)
 	}
 	
 	// Procesar órdenes restantes para los certificados de equilibrio
 	if len(remainingOrders) > 0 {
-		// Si no hay órdenes restantes, no hay nada más que hacer
-		// Calcular el monto total restante
-		remainingAmount := 0.0
-		for _, order := range remainingOrders {
-			remainingAmount += order.Amount
+		balanceCertificates, _, err := balancePhase(ctx, remainingOrders, reservedCertificates, limitAmount, opts, certificateID)
+		if err != nil {
+			// El reparto depende de todas las órdenes restantes: se descarta lo
+			// armado y la fase completa queda para cuando se reanude
+			pendingOrders, ctxErr = remainingOrders, err
+		} else {
+			certificates = append(certificates, balanceCertificates...)
 		}
-		
-		// Calcular el monto objetivo por certificado de equilibrio
-		targetAmountPerBalanceCert := remainingAmount / float64(reservedCertificates)
-		if targetAmountPerBalanceCert > limitAmount {
-			targetAmountPerBalanceCert = limitAmount * 0.9 // Ajustar para no exceder el límite
+	}
+	
+	// Verificación final para todos los certificados
+	for _, cert := range certificates {
+		if cert.Amount > maxAmount {
+			// Esto es una verificación de seguridad, no debería ocurrir
+			opts.warnf("certificado %d excede el límite: $%.2f", cert.ID, cert.Amount)
 		}
-		
-		// Crear certificados de equilibrio
-		currentBalanceCert := CertificateBuilder{}
-		balanceCertCount := 0
-		
-		for _, order := range remainingOrders {
-			// PRIMERO verificamos si añadir esta orden excedería el límite absoluto
-			if currentBalanceCert.Amount + order.Amount > limitAmount {
-				// Finalizar este certificado
-				certificates = append(certificates, Certificate{
-					ID:     certificateID,
-					Amount: currentBalanceCert.Amount,
-					Orders: append([]Order{}, currentBalanceCert.Orders...),
-				})
-				certificateID++
-				balanceCertCount++
-				
-				// Comenzar un nuevo certificado con esta orden
-				currentBalanceCert = CertificateBuilder{
-					Orders: []Order{order},
-					Amount: order.Amount,
-				}
-				continue // Continuar con la siguiente orden
-			}
-			
-			// Si este certificado ya está cerca del objetivo y añadir esta orden lo sobrepasaría significativamente
-			if currentBalanceCert.Amount > 0 && 
-			   currentBalanceCert.Amount >= targetAmountPerBalanceCert * 0.85 && 
-			   currentBalanceCert.Amount + order.Amount > targetAmountPerBalanceCert * 1.15 &&
-			   balanceCertCount < reservedCertificates - 1 {
-				// Finalizar este certificado
-				certificates = append(certificates, Certificate{
-					ID:     certificateID,
-					Amount: currentBalanceCert.Amount,
-					Orders: append([]Order{}, currentBalanceCert.Orders...),
-				})
-				certificateID++
-				balanceCertCount++
-				
-				// Comenzar un nuevo certificado con esta orden
-				currentBalanceCert = CertificateBuilder{
-					Orders: []Order{order},
-					Amount: order.Amount,
-				}
-			} else {
-				// Añadir la orden al certificado actual
-				currentBalanceCert.Orders = append(currentBalanceCert.Orders, order)
-				currentBalanceCert.Amount += order.Amount
+	}
+	
+	return certificates, pendingOrders, ctxErr
+}
+
+// balancePhase reparte las órdenes que no entraron en la fase principal, en el
+// orden recibido, en alrededor de reservedCertificates certificados de
+// equilibrio de monto parejo, sin superar limitAmount ni las restricciones de
+// opts. Los IDs comienzan en firstID. Si ctx termina antes, devuelve lo armado,
+// las órdenes sin procesar y el error del contexto.
+func balancePhase(ctx context.Context, remainingOrders []Order, reservedCertificates int, limitAmount float64, opts PackOptions, firstID int) ([]Certificate, []Order, error) {
+	var certificates []Certificate
+	var pendingOrders []Order
+	var ctxErr error
+	certificateID := firstID
+	maxAmount := limitWithTolerance(limitAmount, &opts)
+
+	// Calcular el monto total restante
+	remainingAmount := 0.0
+	for _, order := range remainingOrders {
+		remainingAmount += order.Amount
+	}
+	
+	// Calcular el monto objetivo por certificado de equilibrio
+	targetAmountPerBalanceCert := remainingAmount / float64(reservedCertificates)
+	if targetAmountPerBalanceCert > limitAmount {
+		targetAmountPerBalanceCert = limitAmount * 0.9 // Ajustar para no exceder el límite
+	}
+	
+	// Crear certificados de equilibrio, reservando de antemano el espacio
+	// para la cantidad promedio de órdenes de cada uno
+	ordersPerBalanceCert := len(remainingOrders)/reservedCertificates + 1
+	newBalanceCert := func() certificateBuilder {
+		builder := newCertificateBuilder(&opts)
+		builder.Orders = make([]Order, 0, ordersPerBalanceCert)
+		return builder
+	}
+	currentBalanceCert := newBalanceCert()
+	balanceCertCount := 0
+	
+	for idx, order := range remainingOrders {
+		if idx%contextCheckInterval == 0 {
+			if ctxErr = ctx.Err(); ctxErr != nil {
+				pendingOrders = remainingOrders[idx:]
+				break
 			}
 		}
 		
-		// Añadir el último certificado de equilibrio si hay órdenes pendientes
-		if len(currentBalanceCert.Orders) > 0 {
-			// Verificación final para asegurar que ningún certificado exceda el límite
-			if currentBalanceCert.Amount > limitAmount {
-				fmt.Printf("ERROR: Último certificado ID %d excede el límite: $%.2f\n", 
-					certificateID, currentBalanceCert.Amount)
-				// Esto no debería ocurrir dado nuestro algoritmo, pero verificamos por seguridad
-			}
+		// PRIMERO verificamos si añadir esta orden excedería el límite absoluto
+		// o las restricciones del certificado
+		if len(currentBalanceCert.Orders) > 0 && !currentBalanceCert.fits(order, maxAmount, &opts) {
+			// Finalizar este certificado
+			certificates = append(certificates, Certificate{
+				ID:     certificateID,
+				Amount: currentBalanceCert.Amount,
+				Orders: currentBalanceCert.Orders,
+			})
+			certificateID++
+			balanceCertCount++
 			
+			// Comenzar un nuevo certificado con esta orden
+			currentBalanceCert = newBalanceCert()
+			currentBalanceCert.add(order)
+			continue // Continuar con la siguiente orden
+		}
+		
+		// Si este certificado ya está cerca del objetivo y añadir esta orden lo sobrepasaría significativamente
+		if currentBalanceCert.Amount > 0 && 
+		   currentBalanceCert.Amount >= targetAmountPerBalanceCert * 0.85 && 
+		   currentBalanceCert.Amount + order.Amount > targetAmountPerBalanceCert * 1.15 &&
+		   balanceCertCount < reservedCertificates - 1 {
+			// Finalizar este certificado
 			certificates = append(certificates, Certificate{
 				ID:     certificateID,
 				Amount: currentBalanceCert.Amount,
-				Orders: append([]Order{}, currentBalanceCert.Orders...),
+				Orders: currentBalanceCert.Orders,
 			})
+			certificateID++
+			balanceCertCount++
+			
+			// Comenzar un nuevo certificado con esta orden
+			currentBalanceCert = newBalanceCert()
+			currentBalanceCert.add(order)
+		} else {
+			// Añadir la orden al certificado actual
+			currentBalanceCert.add(order)
 		}
 	}
 	
-	// Verificación final para todos los certificados
-	for _, cert := range certificates {
-		if cert.Amount > limitAmount {
-			fmt.Printf("ERROR CRÍTICO: Certificado final ID %d excede el límite: $%.2f\n", 
-				cert.ID, cert.Amount)
-			// Esto es una verificación de seguridad, no debería ocurrir
-		}
+	// Añadir el último certificado de equilibrio si hay órdenes pendientes
+	if len(currentBalanceCert.Orders) > 0 {
+		certificates = append(certificates, Certificate{
+			ID:     certificateID,
+			Amount: currentBalanceCert.Amount,
+			Orders: currentBalanceCert.Orders,
+		})
 	}
 	
-	return certificates
+	return certificates, pendingOrders, ctxErr
 }
-	
 
 func main() {
-	fmt.Println("Iniciando generación de órdenes...")
+	// Ctrl-C cancela el contexto solo en los subcomandos que lo atienden, para
+	// que terminen guardando lo que alcanzaron a procesar; en los demás conserva
+	// su efecto habitual de terminar el proceso
+	ctx, stop := context.Background(), func() {}
+	if len(os.Args) > 1 && cancelable[os.Args[1]] {
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+	}
+	err := run(ctx, os.Args[1:], os.Stdout)
+	stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runDemo ejecuta la simulación completa original: genera las órdenes de los
+// 3500 comerciantes, las empaqueta y muestra las estadísticas
+func runDemo(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("fcb", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "mostrar estadísticas adicionales")
+	format := fs.String("format", "text", "formato de salida: text, o json para emitir solo el resumen")
+	configPath := fs.String("config", "", "archivo de configuración JSON con las opciones de generación y empaquetado")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Generar certificados con un límite de $500,000 por certificado, salvo que
+	// la configuración indique otro
+	genOpts := DefaultGenOptions()
+	packOpts := PackOptions{Limit: defaultCertificateLimit}
+	if *configPath != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err != nil {
+			return err
+		}
+		genOpts, packOpts = cfg.Gen, cfg.Pack
+	}
+	out := w
+	switch *format {
+	case "text":
+	case "json":
+		// Solo el resumen: los mensajes y estadísticas legibles se descartan
+		w = io.Discard
+		genOpts.ProgressEvery = 0
+	default:
+		return fmt.Errorf("formato de salida desconocido: %q", *format)
+	}
+
+	fmt.Fprintln(w, "Iniciando generación de órdenes...")
 	startTime := time.Now()
-	
-	orders, err := generateOrders()
+
+	orders, err := generateOrders(genOpts)
 	if err != nil {
-		fmt.Printf("Error al generar órdenes: %v\n", err)
-		return
+		return fmt.Errorf("error al generar órdenes: %w", err)
 	}
-	
+
 	elapsed := time.Since(startTime)
 	totalOrders := len(orders)
-	fmt.Printf("Se generaron %d órdenes en %v\n", totalOrders, elapsed)
-	
+	fmt.Fprintf(w, "Se generaron %d órdenes en %v\n", totalOrders, elapsed)
+
 	// Mostrar algunas órdenes de ejemplo
-	fmt.Println("\nEjemplo de las primeras 5 órdenes:")
+	fmt.Fprintln(w, "\nEjemplo de las primeras 5 órdenes:")
 	for i := 0; i < 5 && i < len(orders); i++ {
-		fmt.Printf("  Orden ID: %d, Comerciante: %d, Monto: $%.2f\n", 
+		fmt.Fprintf(w, "  Orden ID: %d, Comerciante: %d, Monto: $%.2f\n",
 			orders[i].ID, orders[i].MerchantID, orders[i].Amount)
 	}
-	
+
 	// Calcular el monto total de todas las órdenes
 	var totalAmount float64
 	for _, order := range orders {
 		totalAmount += order.Amount
 	}
-	
-	// Generar certificados con un límite de $500,000 por certificado
-	const certificateLimitAmount = 500000.0
-	certificates := generateCertificates(orders, certificateLimitAmount)
-	
-	// Calcular estadísticas de certificados
-	var totalCertificateAmount float64
-	var minCertAmount float64 = float64(^uint(0) >> 1) // Valor máximo para float64
-	var maxCertAmount float64 = 0
-	certificateAmounts := make([]float64, len(certificates))
-	
-	for i, cert := range certificates {
-		totalCertificateAmount += cert.Amount
-		certificateAmounts[i] = cert.Amount
-		
-		if cert.Amount < minCertAmount {
-			minCertAmount = cert.Amount
-		}
-		if cert.Amount > maxCertAmount {
-			maxCertAmount = cert.Amount
-		}
+
+	certificateLimitAmount := packOpts.Limit
+	packStart := time.Now()
+	result := GenerateCertificates(orders, packOpts)
+	packingTime := time.Since(packStart)
+	certificates := result.Certificates
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "ADVERTENCIA: %s\n", warning)
 	}
-	
+
 	// Calcular el número de certificados teórico basado en la división del monto total
 	theoreticalNumCertificates := totalAmount / certificateLimitAmount
-	
-	// Calcular el porcentaje promedio de llenado de los certificados
-	avgFillPercentage := (totalCertificateAmount / float64(len(certificates))) / certificateLimitAmount * 100
-	
-	// Ordenar los montos para calcular percentiles
-	sort.Float64s(certificateAmounts)
-	
-	// Calcular percentiles relevantes
-	p25 := percentile(certificateAmounts, 25)
-	p50 := percentile(certificateAmounts, 50) // mediana
-	p75 := percentile(certificateAmounts, 75)
-	p90 := percentile(certificateAmounts, 90)
-	
+
 	// Mostrar estadísticas
-	fmt.Println("\nEstadísticas:")
-	fmt.Printf("  Número total de comerciantes: 3,500\n")
-	fmt.Printf("  Órdenes por comerciante: 612\n")
-	fmt.Printf("  Número total de órdenes: %d\n", totalOrders)
-	fmt.Printf("  Monto total de órdenes: $%.2f\n", totalAmount)
-	fmt.Printf("  Límite por certificado: $%.2f\n", certificateLimitAmount)
-	fmt.Printf("  Número teórico de certificados (total/500K): %.2f\n", theoreticalNumCertificates)
-	fmt.Printf("  Número real de certificados generados: %d\n", len(certificates))
-	fmt.Printf("  Porcentaje promedio de llenado: %.2f%%\n", avgFillPercentage)
-	
-	fmt.Println("\nDistribución de montos en certificados:")
-	fmt.Printf("  Monto mínimo: $%.2f (%.2f%% del límite)\n", minCertAmount, minCertAmount/certificateLimitAmount*100)
-	fmt.Printf("  Percentil 25: $%.2f (%.2f%% del límite)\n", p25, p25/certificateLimitAmount*100)
-	fmt.Printf("  Mediana (P50): $%.2f (%.2f%% del límite)\n", p50, p50/certificateLimitAmount*100)
-	fmt.Printf("  Percentil 75: $%.2f (%.2f%% del límite)\n", p75, p75/certificateLimitAmount*100)
-	fmt.Printf("  Percentil 90: $%.2f (%.2f%% del límite)\n", p90, p90/certificateLimitAmount*100)
-	fmt.Printf("  Monto máximo: $%.2f (%.2f%% del límite)\n", maxCertAmount, maxCertAmount/certificateLimitAmount*100)
-	
-	if len(certificates) > 0 {
-		// Mostrar ejemplo de certificados (primeros y últimos)
-		fmt.Println("\nPrimeros 3 certificados:")
-		for i := 0; i < 3 && i < len(certificates); i++ {
-			fmt.Printf("  Certificado ID: %d, Monto: $%.2f (%.2f%%), Órdenes: %d\n", 
-				certificates[i].ID, certificates[i].Amount, 
-				certificates[i].Amount/certificateLimitAmount*100, len(certificates[i].Orders))
-		}
-		
-		fmt.Println("\nÚltimos 3 certificados (de equilibrio):")
-		for i := len(certificates) - 3; i < len(certificates); i++ {
-			fmt.Printf("  Certificado ID: %d, Monto: $%.2f (%.2f%%), Órdenes: %d\n", 
-				certificates[i].ID, certificates[i].Amount,
-				certificates[i].Amount/certificateLimitAmount*100, len(certificates[i].Orders))
-		}
+	fmt.Fprintln(w, "\nEstadísticas:")
+	fmt.Fprintf(w, "  Número total de comerciantes: %d\n", genOpts.Merchants)
+	fmt.Fprintf(w, "  Órdenes por comerciante: %d\n", genOpts.OrdersPerMerchant)
+	fmt.Fprintf(w, "  Número total de órdenes: %d\n", totalOrders)
+	fmt.Fprintf(w, "  Monto total de órdenes: $%.2f\n", totalAmount)
+	fmt.Fprintf(w, "  Número teórico de certificados (total/límite): %.2f\n", theoreticalNumCertificates)
+	fmt.Fprintf(w, "  Empaquetado en %v (%.0f órdenes/s)\n", packingTime, Throughput(totalOrders, packingTime))
+
+	printCertificateReport(w, certificates, certificateLimitAmount, *verbose)
+
+	if *format == "json" {
+		summary := NewRunSummary(orders, certificates, certificateLimitAmount)
+		summary.SetPackingTime(packingTime)
+		return json.NewEncoder(out).Encode(summary)
 	}
+	return nil
 }
 
 // Función para calcular percentiles
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestProgressEveryTenOverThirtyMerchants(t *testing.T) {
+	var calls [][3]int
+	_, err := generateOrders(GenOptions{
+		Merchants:         30,
+		OrdersPerMerchant: 2,
+		Seed:              1,
+		ProgressEvery:     10,
+		OnProgress: func(done, total, orders int) {
+			calls = append(calls, [3]int{done, total, orders})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][3]int{{10, 30, 20}, {20, 30, 40}, {30, 30, 60}}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress callbacks = %v, want %v", calls, want)
+	}
+}
+
+func TestProgressEveryZeroDisablesReports(t *testing.T) {
+	fired := false
+	_, err := generateOrders(GenOptions{
+		Merchants:         30,
+		OrdersPerMerchant: 2,
+		Seed:              1,
+		OnProgress:        func(int, int, int) { fired = true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired {
+		t.Error("OnProgress fired with ProgressEvery = 0")
+	}
+}
+
+func TestMainPhaseCertificatesBudget(t *testing.T) {
+	var orders []Order
+	for i := range 120 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*7%890), MerchantID: i%10 + 1})
+	}
+	const limit = 2000
+
+	// Con el presupuesto fijado, la fase principal ocupa exactamente los
+	// primeros IDs y todo lo que va después pasó por la fase de equilibrio
+	balanced := func(budget int) int {
+		result := GenerateCertificates(orders, PackOptions{Limit: limit, MainPhaseCertificates: budget})
+		if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+			t.Fatalf("budget %d: VerifyCertificates: %v", budget, err)
+		}
+		n := 0
+		for _, cert := range result.Certificates {
+			if cert.ID > budget {
+				n += len(cert.Orders)
+			}
+		}
+		return n
+	}
+
+	small, large := balanced(5), balanced(25)
+	if small == 0 {
+		t.Fatal("a budget of 5 left no orders for the balance phase")
+	}
+	if large >= small {
+		t.Errorf("balance-phase orders: %d with budget 25, %d with budget 5; want fewer with the larger budget", large, small)
+	}
+}
+
+// benchmarkOrders genera una vez el conjunto de los benchmarks de empaquetado,
+// con la forma de la simulación original pero más chico
+func benchmarkOrders(b *testing.B) []Order {
+	b.Helper()
+	orders, err := generateOrders(GenOptions{Merchants: 200, OrdersPerMerchant: 500, Seed: 1})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return orders
+}
+
+func BenchmarkGenerateCertificates(b *testing.B) {
+	orders := benchmarkOrders(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		GenerateCertificates(orders, PackOptions{})
+	}
+}
+
+func TestGenerateCertificatesAllocationBudget(t *testing.T) {
+	orders, err := generateOrders(GenOptions{Merchants: 20, OrdersPerMerchant: 500, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := PackOptions{Limit: 50000}
+	if n := len(GenerateCertificates(orders, opts).Certificates); n != 101 {
+		t.Fatalf("got %d certificates, want the 101 the budget was measured with", n)
+	}
+
+	// Unas 600 asignaciones para 10.000 órdenes en 101 certificados; copiar las
+	// órdenes de cada certificado o dejar crecer los slices sin reservar pasa
+	// holgadamente de 700
+	const budget = 700
+	if allocs := testing.AllocsPerRun(5, func() { GenerateCertificates(orders, opts) }); allocs > budget {
+		t.Errorf("GenerateCertificates made %.0f allocations, over the budget of %d", allocs, budget)
+	}
+}
+
+func BenchmarkBalancePack(b *testing.B) {
+	orders := benchmarkOrders(b)[:20000]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		BalancePack(orders, 30, defaultCertificateLimit)
+	}
+}
+
+// BenchmarkInputPreSorted compara el empaquetado de órdenes ya ordenadas con y
+// sin InputPreSorted; la diferencia es el ordenamiento que se evita
+func BenchmarkInputPreSorted(b *testing.B) {
+	orders := benchmarkOrders(b)
+	sort.Slice(orders, func(i, j int) bool {
+		return amountDescending(orders[i], orders[j])
+	})
+	for _, presorted := range []bool{false, true} {
+		b.Run(fmt.Sprintf("presorted=%v", presorted), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				GenerateCertificates(orders, PackOptions{InputPreSorted: presorted})
+			}
+		})
+	}
+}
+
+func TestDisableBalancePhaseKeepsCertificatesFull(t *testing.T) {
+	var orders []Order
+	for i := range 240 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(500 + i*7919%9500), MerchantID: i%40 + 1})
+	}
+	const limit = 100000
+
+	fills := func(opts PackOptions) []float64 {
+		opts.Limit = limit
+		result := GenerateCertificates(orders, opts)
+		if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+			t.Fatalf("VerifyCertificates: %v", err)
+		}
+		var fills []float64
+		for _, cert := range result.Certificates {
+			fills = append(fills, cert.Amount/limit)
+		}
+		return fills
+	}
+
+	// Certificados por debajo del 99% sin contar el último, que se queda con el resto
+	underfilled := func(fills []float64) int {
+		n := 0
+		for _, fill := range fills[:len(fills)-1] {
+			if fill < 0.99 {
+				n++
+			}
+		}
+		return n
+	}
+
+	// La fase de equilibrio reparte las últimas órdenes en certificados parejos
+	// pero menos llenos
+	withBalance := fills(PackOptions{})
+	if n := underfilled(withBalance); n < 2 {
+		t.Fatalf("balance phase left %d certificates under 99%%, want its under-filled tail as a baseline", n)
+	}
+
+	pure := fills(PackOptions{DisableBalancePhase: true})
+	if n := underfilled(pure); n != 0 {
+		t.Errorf("without the balance phase %d certificates besides the last are under 99%%: %v", n, pure)
+	}
+	if len(pure) > len(withBalance) {
+		t.Errorf("got %d certificates without the balance phase, want at most the %d with it", len(pure), len(withBalance))
+	}
+}
+
+func TestOrderAtLimitGetsOwnCertificate(t *testing.T) {
+	const limit = 1000
+	orders := []Order{
+		{ID: 1, Amount: 300, MerchantID: 1},
+		{ID: 2, Amount: limit, MerchantID: 2},
+		{ID: 3, Amount: 0.01, MerchantID: 3}, // Entraría en cualquier certificado con un centavo libre
+		{ID: 4, Amount: 650, MerchantID: 1},
+		{ID: 5, Amount: limit, MerchantID: 3},
+		{ID: 6, Amount: 200, MerchantID: 4},
+	}
+
+	for _, disable := range []bool{false, true} {
+		result := GenerateCertificates(orders, PackOptions{Limit: limit, DisableBalancePhase: disable})
+		if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+			t.Fatalf("DisableBalancePhase %v: VerifyCertificates: %v", disable, err)
+		}
+		atLimit := 0
+		for _, cert := range result.Certificates {
+			for _, order := range cert.Orders {
+				if order.Amount != limit {
+					continue
+				}
+				atLimit++
+				if len(cert.Orders) != 1 {
+					t.Errorf("DisableBalancePhase %v: order %d shares certificate %d with %d others",
+						disable, order.ID, cert.ID, len(cert.Orders)-1)
+				}
+			}
+		}
+		if atLimit != 2 {
+			t.Errorf("DisableBalancePhase %v: found %d orders at the limit, want 2", disable, atLimit)
+		}
+	}
+}
+
+func TestMaxTotalOrdersTruncatesGeneration(t *testing.T) {
+	// 10 comerciantes de 25 órdenes darían 250; el tope corta en medio del quinto
+	opts := GenOptions{Merchants: 10, OrdersPerMerchant: 25, Seed: 8, MaxTotalOrders: 110}
+	orders, err := generateOrders(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 110 {
+		t.Fatalf("generated %d orders, want exactly 110", len(orders))
+	}
+	perMerchant := make(map[int]int)
+	for _, order := range orders {
+		perMerchant[order.MerchantID]++
+	}
+	want := map[int]int{1: 25, 2: 25, 3: 25, 4: 25, 5: 10}
+	if !reflect.DeepEqual(perMerchant, want) {
+		t.Errorf("orders per merchant = %v, want %v", perMerchant, want)
+	}
+
+	// Un tope por encima del total natural no cambia nada
+	opts.MaxTotalOrders = 1000
+	if orders, err := generateOrders(opts); err != nil || len(orders) != 250 {
+		t.Errorf("cap above the natural total: %d orders, err %v; want 250", len(orders), err)
+	}
+}
+
+func TestMerchantProfilesClusterAroundMerchantMean(t *testing.T) {
+	// variances devuelve la varianza promedio de los montos de cada comerciante
+	// alrededor de su propia media y la varianza de todos los montos juntos
+	variances := func(orders []Order) (within, across float64) {
+		byMerchant := make(map[int][]float64)
+		var all []float64
+		for _, order := range orders {
+			byMerchant[order.MerchantID] = append(byMerchant[order.MerchantID], order.Amount)
+			all = append(all, order.Amount)
+		}
+		variance := func(values []float64) float64 {
+			var sum, sumSq float64
+			for _, v := range values {
+				sum += v
+			}
+			mean := sum / float64(len(values))
+			for _, v := range values {
+				sumSq += (v - mean) * (v - mean)
+			}
+			return sumSq / float64(len(values))
+		}
+		for _, amounts := range byMerchant {
+			within += variance(amounts)
+		}
+		return within / float64(len(byMerchant)), variance(all)
+	}
+
+	generate := func(profiles bool) []Order {
+		orders, err := generateOrders(GenOptions{Merchants: 40, OrdersPerMerchant: 100, Seed: 3, MerchantProfiles: profiles})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, order := range orders {
+			if order.Amount < 10 || order.Amount > 1000 {
+				t.Fatalf("order %d amount %.2f outside [10, 1000]", order.ID, order.Amount)
+			}
+		}
+		return orders
+	}
+
+	// Con perfiles, cada comerciante se aparta de su media un 10%: mucho menos
+	// que lo que varían los montos entre comerciantes
+	within, across := variances(generate(true))
+	if within >= across/10 {
+		t.Errorf("with profiles: within-merchant variance %.0f, want below a tenth of the overall %.0f", within, across)
+	}
+
+	// Sin perfiles, todos los comerciantes sortean en el mismo rango uniforme
+	within, across = variances(generate(false))
+	if within < across/2 {
+		t.Errorf("without profiles: within-merchant variance %.0f, want close to the overall %.0f", within, across)
+	}
+}
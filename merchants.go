@@ -0,0 +1,115 @@
+package main
+
+import "sort"
+
+// MerchantCertificateCount empaqueta solo las órdenes del comerciante indicado y
+// devuelve cuántos certificados resultan, sin procesar el conjunto completo.
+func MerchantCertificateCount(orders []Order, merchantID int, limit float64) int {
+	var merchantOrders []Order
+	for _, order := range orders {
+		if order.MerchantID == merchantID {
+			merchantOrders = append(merchantOrders, order)
+		}
+	}
+	if len(merchantOrders) == 0 {
+		return 0
+	}
+
+	return len(GenerateCertificates(merchantOrders, PackOptions{Limit: limit}).Certificates)
+}
+
+// MerchantSpread devuelve, para cada comerciante, en cuántos certificados
+// distintos aparecen sus órdenes. Un valor mayor indica más fragmentación.
+func MerchantSpread(certs []Certificate) map[int]int {
+	spread := make(map[int]int)
+	for _, cert := range certs {
+		seen := make(map[int]bool)
+		for _, order := range cert.Orders {
+			if !seen[order.MerchantID] {
+				seen[order.MerchantID] = true
+				spread[order.MerchantID]++
+			}
+		}
+	}
+	return spread
+}
+
+// MerchantFillQuality devuelve, para cada comerciante, el llenado promedio (en
+// porcentaje de limit, o del Limit propio del certificado si lo tiene) de los
+// certificados distintos que tocan sus órdenes. Cada certificado cuenta una vez
+// por comerciante aunque tenga varias de sus órdenes. Un valor bajo señala a los
+// comerciantes que terminan en certificados poco llenos.
+func MerchantFillQuality(certs []Certificate, limit float64) map[int]float64 {
+	sums := make(map[int]float64)
+	for _, cert := range certs {
+		certLimit := limit
+		if cert.Limit > 0 {
+			certLimit = cert.Limit
+		}
+		fill := cert.Amount / certLimit * 100
+
+		seen := make(map[int]bool)
+		for _, order := range cert.Orders {
+			if !seen[order.MerchantID] {
+				seen[order.MerchantID] = true
+				sums[order.MerchantID] += fill
+			}
+		}
+	}
+
+	quality := make(map[int]float64, len(sums))
+	for merchantID, spread := range MerchantSpread(certs) {
+		quality[merchantID] = sums[merchantID] / float64(spread)
+	}
+	return quality
+}
+
+// CohesionViolations devuelve, de menor a mayor, los IDs de los comerciantes
+// cuyas órdenes quedaron repartidas en varios certificados aunque su total
+// cabría en uno solo con limit. Sirve para revisar el resultado de
+// MerchantCohesion, que solo debería partir a los comerciantes que superan el
+// límite.
+func CohesionViolations(certs []Certificate, limit float64) []int {
+	totals := make(map[int]float64)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			totals[order.MerchantID] += order.Amount
+		}
+	}
+
+	var violations []int
+	for merchantID, spread := range MerchantSpread(certs) {
+		if spread > 1 && totals[merchantID] <= limit+defaultEpsilon {
+			violations = append(violations, merchantID)
+		}
+	}
+	sort.Ints(violations)
+	return violations
+}
+
+// PartitionOrders reparte las órdenes en parts particiones independientes, por
+// ejemplo para empaquetarlas en distintas máquinas, sin partir a ningún
+// comerciante. Los comerciantes se asignan de mayor a menor monto total a la
+// partición con menor monto acumulado (la heurística LPT), lo que deja los
+// totales parejos salvo que un comerciante concentre una parte grande del monto.
+// Dentro de cada partición las órdenes de un comerciante quedan de mayor a
+// menor monto. Con parts < 1 devuelve nil.
+func PartitionOrders(orders []Order, parts int) [][]Order {
+	if parts < 1 {
+		return nil
+	}
+
+	partitions := make([][]Order, parts)
+	totals := make([]float64, parts)
+	for _, group := range groupByMerchant(orders, true) {
+		lightest := 0
+		for i := range totals {
+			if totals[i] < totals[lightest] {
+				lightest = i
+			}
+		}
+		partitions[lightest] = append(partitions[lightest], group.orders...)
+		totals[lightest] += group.total
+	}
+	return partitions
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMerchantCertificateCountTotalOverLimit(t *testing.T) {
+	// El comercio 7 suma $1.2M en 12 órdenes de $100K; con $500K caben 5 por certificado
+	var orders []Order
+	for i := range 12 {
+		orders = append(orders, Order{ID: i + 1, Amount: 100000, MerchantID: 7})
+	}
+	// Órdenes de otro comercio que no deben influir en la cuenta
+	for i := range 5 {
+		orders = append(orders, Order{ID: 100 + i, Amount: 450000, MerchantID: 8})
+	}
+
+	if got := MerchantCertificateCount(orders, 7, 500000); got != 3 {
+		t.Errorf("MerchantCertificateCount(7) = %d, want 3", got)
+	}
+	if got := MerchantCertificateCount(orders, 99, 500000); got != 0 {
+		t.Errorf("MerchantCertificateCount for an unknown merchant = %d, want 0", got)
+	}
+}
+
+func TestMerchantSpreadSplitAcrossThree(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Orders: []Order{{ID: 1, MerchantID: 5}, {ID: 2, MerchantID: 5}, {ID: 3, MerchantID: 9}}},
+		{ID: 2, Orders: []Order{{ID: 4, MerchantID: 5}, {ID: 5, MerchantID: 9}}},
+		{ID: 3, Orders: []Order{{ID: 6, MerchantID: 5}, {ID: 7, MerchantID: 2}}},
+	}
+	want := map[int]int{5: 3, 9: 2, 2: 1}
+	if got := MerchantSpread(certs); !reflect.DeepEqual(got, want) {
+		t.Errorf("MerchantSpread = %v, want %v", got, want)
+	}
+}
+
+func TestCohesionViolationsFlagsNeedlessSplit(t *testing.T) {
+	const limit = 1000
+	certs := []Certificate{
+		{ID: 1, Amount: 900, Orders: []Order{
+			{ID: 1, Amount: 600, MerchantID: 1},
+			{ID: 2, Amount: 150, MerchantID: 2},
+			{ID: 3, Amount: 150, MerchantID: 3},
+		}},
+		{ID: 2, Amount: 1000, Orders: []Order{
+			{ID: 4, Amount: 700, MerchantID: 1}, // El 1 suma 1300: partirlo es inevitable
+			{ID: 5, Amount: 200, MerchantID: 2}, // El 2 suma 350: cabía entero en uno
+			{ID: 6, Amount: 100, MerchantID: 4},
+		}},
+		{ID: 3, Amount: 1000, Orders: []Order{
+			{ID: 7, Amount: 850, MerchantID: 3}, // El 3 suma exactamente el límite
+			{ID: 8, Amount: 150, MerchantID: 5},
+		}},
+	}
+
+	got := CohesionViolations(certs, limit)
+	if want := []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("CohesionViolations = %v, want %v", got, want)
+	}
+
+	// Con la cohesión activada no debería quedar ninguno
+	var orders []Order
+	for _, cert := range certs {
+		orders = append(orders, cert.Orders...)
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: limit, MerchantCohesion: true})
+	if v := CohesionViolations(result.Certificates, limit); len(v) != 0 {
+		t.Errorf("MerchantCohesion packing has violations %v", v)
+	}
+}
+
+func TestPartitionOrdersKeepsMerchantsWhole(t *testing.T) {
+	var orders []Order
+	id := 1
+	for merchant := 1; merchant <= 30; merchant++ {
+		// Comerciantes de tamaños distintos, con órdenes intercaladas en la entrada
+		for i := range 3 + merchant%7 {
+			orders = append(orders, Order{ID: id, Amount: float64(100 + (merchant*31+i*17)%400), MerchantID: merchant})
+			id++
+		}
+	}
+	slices.SortFunc(orders, func(a, b Order) int { return a.ID%7 - b.ID%7 })
+
+	const parts = 4
+	partitions := PartitionOrders(orders, parts)
+	if len(partitions) != parts {
+		t.Fatalf("got %d partitions, want %d", len(partitions), parts)
+	}
+
+	partitionOf := make(map[int]int)
+	count := 0
+	var totals []float64
+	for p, partition := range partitions {
+		var total float64
+		for _, order := range partition {
+			if other, ok := partitionOf[order.MerchantID]; ok && other != p {
+				t.Errorf("merchant %d is split between partitions %d and %d", order.MerchantID, other, p)
+			}
+			partitionOf[order.MerchantID] = p
+			total += order.Amount
+		}
+		count += len(partition)
+		totals = append(totals, total)
+	}
+	if count != len(orders) {
+		t.Errorf("partitions hold %d orders, want %d", count, len(orders))
+	}
+
+	// LPT deja la diferencia por debajo del total del comerciante más grande
+	var largest float64
+	for _, group := range groupByMerchant(orders, true) {
+		largest = max(largest, group.total)
+	}
+	if spread := slices.Max(totals) - slices.Min(totals); spread > largest {
+		t.Errorf("partition totals %v differ by %.2f, want at most the largest merchant total %.2f", totals, spread, largest)
+	}
+}
+
+func TestMerchantFillQualityFromTouchedCertificates(t *testing.T) {
+	const limit = 1000
+	orders := []Order{
+		{ID: 1, Amount: 600, MerchantID: 1},
+		{ID: 2, Amount: 500, MerchantID: 3},
+		{ID: 3, Amount: 400, MerchantID: 2},
+		{ID: 4, Amount: 300, MerchantID: 1},
+		{ID: 5, Amount: 100, MerchantID: 3},
+	}
+	certs := GenerateCertificates(orders, PackOptions{Limit: limit}).Certificates
+	// FFD deja {600, 400} lleno y {500, 300, 100} al 90%
+	if len(certs) != 2 || !slices.Equal(sortedOrderIDs(certs[0]), []int{1, 3}) ||
+		!slices.Equal(sortedOrderIDs(certs[1]), []int{2, 4, 5}) {
+		t.Fatalf("unexpected packing %+v", certs)
+	}
+
+	// El 1 está en ambos; el 3 tiene dos órdenes en el segundo, que cuenta una vez
+	want := map[int]float64{1: 95, 2: 100, 3: 90}
+	if got := MerchantFillQuality(certs, limit); !reflect.DeepEqual(got, want) {
+		t.Errorf("MerchantFillQuality = %v, want %v", got, want)
+	}
+
+	// Un certificado con límite propio se mide contra ese límite
+	certs = append(certs, Certificate{ID: 3, Amount: 500, Limit: 2000, Orders: []Order{{ID: 6, Amount: 500, MerchantID: 2}}})
+	want[2] = (100 + 25) / 2.0
+	if got := MerchantFillQuality(certs, limit); !reflect.DeepEqual(got, want) {
+		t.Errorf("with a per-certificate limit: MerchantFillQuality = %v, want %v", got, want)
+	}
+}
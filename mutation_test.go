@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// cloneOrders copia las órdenes junto con sus Tags, para comparar después
+// contra un original que la función probada no pudo tocar
+func cloneOrders(orders []Order) []Order {
+	clone := slices.Clone(orders)
+	for i := range clone {
+		clone[i].Tags = maps.Clone(clone[i].Tags)
+	}
+	return clone
+}
+
+// cloneCertificates copia los certificados y, en profundidad, sus órdenes
+func cloneCertificates(certs []Certificate) []Certificate {
+	clone := slices.Clone(certs)
+	for i := range clone {
+		clone[i].Orders = cloneOrders(clone[i].Orders)
+	}
+	return clone
+}
+
+func TestExportedFunctionsDoNotMutateInputs(t *testing.T) {
+	const limit = 3000
+	// Desordenadas, con etiquetas, una por debajo de MinOrderAmount y una que
+	// excede el límite, para que cada camino tenga algo que ordenar o apartar
+	base := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	var orders []Order
+	for i := range 24 {
+		order := Order{
+			ID:         i + 1,
+			Amount:     float64(10 + i*419%980),
+			MerchantID: i%5 + 1,
+			Timestamp:  base.Add(time.Duration(i) * 7 * time.Hour),
+		}
+		if i%3 == 0 {
+			order.Tags = map[string]string{"region": []string{"norte", "sur"}[i%2]}
+		}
+		orders = append(orders, order)
+	}
+	orders[5].Amount = 4
+	orders[17].Amount = 3500
+
+	// Tres órdenes por certificado, con IDs y órdenes fuera de orden
+	var certs []Certificate
+	for i, id := range []int{8, 3, 5, 1, 7, 2, 6, 4} {
+		cert := Certificate{ID: id}
+		for _, order := range []Order{orders[3*i+2], orders[3*i], orders[3*i+1]} {
+			if order.ID == 18 {
+				continue // La que excede el límite queda fuera
+			}
+			cert.Orders = append(cert.Orders, order)
+			cert.Amount += order.Amount
+		}
+		certs = append(certs, cert)
+	}
+
+	var assignment bytes.Buffer
+	if err := WriteAssignmentCSV(&assignment, certs); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := func(opts PackOptions) func([]Order, []Certificate) {
+		return func(o []Order, _ []Certificate) {
+			opts.Limit = limit
+			GenerateCertificates(o, opts)
+		}
+	}
+	netOfFee := func(order Order) float64 { return order.Amount - 5 }
+
+	tests := []struct {
+		name string
+		run  func(o []Order, c []Certificate)
+	}{
+		// Empaquetado en todas sus estrategias y modos
+		{"ffd", pack(PackOptions{})},
+		{"ffi", pack(PackOptions{Strategy: StrategyFirstFitIncreasing})},
+		{"full", pack(PackOptions{Strategy: StrategyMaximizeFullCerts})},
+		{"no-balance", pack(PackOptions{DisableBalancePhase: true})},
+		{"main-phase-budget", pack(PackOptions{MainPhaseCertificates: 2})},
+		{"presorted", pack(PackOptions{InputPreSorted: true})},
+		{"shuffle", pack(PackOptions{ShuffleSeed: 7})},
+		{"sort-key", pack(PackOptions{SortKey: func(o Order) float64 { return float64(o.MerchantID) }})},
+		{"kahan", pack(PackOptions{KahanSummation: true})},
+		{"max-merchants", pack(PackOptions{MaxMerchantsPerCert: 2})},
+		{"cohesion", pack(PackOptions{MerchantCohesion: true})},
+		{"cohesion-sorted", pack(PackOptions{MerchantCohesion: true, SortMerchantsByTotal: true})},
+		{"cohesion-chunked", pack(PackOptions{MerchantCohesion: true, ChunkMerchants: 2})},
+		{"single-merchant", pack(PackOptions{SingleMerchantPerCert: true})},
+		{"effective-amount", pack(PackOptions{EffectiveAmount: netOfFee})},
+		{"soft-limit", pack(PackOptions{SoftLimit: 2000, PenaltyPerDollarOver: 0.1})},
+		{"overflow-limit", pack(PackOptions{OverflowLimit: 4000})},
+		{"min-order", pack(PackOptions{MinOrderAmount: 10})},
+		{"min-cert", pack(PackOptions{MinCertAmount: 2500})},
+		{"groups", pack(PackOptions{Groups: map[int][]int{1: {1, 2, 3}}})},
+		{"anti-affinity", pack(PackOptions{AntiAffinity: [][2]int{{1, 2}, {3, 4}}})},
+		{"id-start", pack(PackOptions{IDStart: 1000, OnCertificate: func(Certificate) {}})},
+		{"pinned", func(o []Order, c []Certificate) {
+			GenerateCertificates(o, PackOptions{Limit: limit, PinnedCertificates: c[:2]})
+		}},
+		{"context-partial", func(o []Order, _ []Certificate) {
+			GenerateCertificatesContext(newCountdownContext(0, context.Canceled), o, PackOptions{Limit: limit, AllowPartial: true})
+		}},
+		{"resume", func(o []Order, c []Certificate) {
+			ResumeCertificates(context.Background(), c[:3], o[9:], PackOptions{Limit: limit, Strategy: StrategyFirstFitIncreasing})
+		}},
+		{"seq", func(o []Order, _ []Certificate) { GenerateCertificatesSeq(slices.Values(o), limit) }},
+		{"balance-pack", func(o []Order, _ []Certificate) { BalancePack(o, 3, limit) }},
+		{"packer", func(o []Order, _ []Certificate) {
+			p := NewPacker(2)
+			<-p.Submit(o, PackOptions{Limit: limit})
+			p.Close()
+		}},
+		{"round-robin", func(o []Order, _ []Certificate) { RoundRobinCertificates(o, limit, 3, []int{2, 1, 1}) }},
+		{"target-count", func(o []Order, _ []Certificate) { GenerateCertificatesTargetCount(o, 4) }},
+		{"minimize-max", func(o []Order, _ []Certificate) { MinimizeMaxAmount(o, 4) }},
+		{"tiered", func(o []Order, _ []Certificate) {
+			GenerateTieredCertificates(o, []CertificateTier{{Limit: 1500}, {Limit: limit, Count: 3}})
+		}},
+		{"optimal", func(o []Order, _ []Certificate) { OptimalPacking(o[:10], limit) }},
+
+		// Órdenes
+		{"shuffle-orders", func(o []Order, _ []Certificate) { ShuffleOrders(o, 3) }},
+		{"filter-orders", func(o []Order, _ []Certificate) {
+			FilterOrders(o, ByMerchant(1, 2), AmountBetween(100, 900), AfterTime(base), OnWeekday)
+		}},
+		{"exclude-orders", func(o []Order, _ []Certificate) { ExcludeOrders(o, map[int]bool{1: true}) }},
+		{"partition", func(o []Order, _ []Certificate) { PartitionOrders(o, 3) }},
+		{"merchant-count", func(o []Order, _ []Certificate) { MerchantCertificateCount(o, 2, limit) }},
+		{"validate-orders", func(o []Order, _ []Certificate) { ValidateOrders(o) }},
+
+		// Estadísticas
+		{"stats", func(o []Order, c []Certificate) {
+			FillCV(c, limit)
+			OrderCountStats(c)
+			MerchantsPerCertStats(c)
+			CorrFillVsOrderCount(c, limit)
+			IssuanceCost(c, 10, 0.5)
+			TagTotals(c, "region")
+			RemainingCapacities(c, limit)
+			MaxCertificateAmount(c)
+			FillBucketCounts(c, limit, 10)
+			MaxAccumulationDrift(c)
+			MinMargin(c, limit)
+			EfficiencyScore(c, o, limit)
+			TopCertificatesByAmount(c, 3)
+			SoftLimitPenalty(c, 2000, 0.1)
+			GiniCoefficient(c)
+			PackingRatio(c, o, limit)
+			MerchantSpread(c)
+			MerchantFillQuality(c, limit)
+			CohesionViolations(c, limit)
+		}},
+		{"order-stats", func(o []Order, _ []Certificate) {
+			TierReport(o, []float64{500, 100})
+			MinCertificatesLowerBound(o, limit)
+			SensitivityToLimit(o, []float64{limit, 5000})
+			MinLimitForCertCount(o, 6)
+		}},
+		{"report", func(o []Order, c []Certificate) {
+			PrintCertificateTree(io.Discard, c, limit, 2)
+			NewRunSummary(o, c, limit)
+		}},
+
+		// Exportación y verificación
+		{"export", func(o []Order, c []Certificate) {
+			WriteCertificatesJSON(io.Discard, c)
+			WriteCertificatesNDJSON(io.Discard, c)
+			WriteAssignmentCSV(io.Discard, c)
+			WriteOrdersCSV(io.Discard, o)
+			AssignmentMatrix(c)
+			FilterCertificates(c, FillBelow(limit, 0.5))
+			FilterCertificates(c, ContainsMerchant(3))
+			SaveCheckpoint(io.Discard, c, o)
+		}},
+		{"per-merchant-reports", func(_ []Order, c []Certificate) { WritePerMerchantReports(t.TempDir(), c) }},
+		{"verify", func(o []Order, c []Certificate) {
+			VerifyCertificates(o, c, limit)
+			MissingOrders(o, c)
+			DigestCertificates(c)
+		}},
+		{"load-assignment", func(o []Order, _ []Certificate) {
+			LoadCertificatesFromAssignment(o, bytes.NewReader(assignment.Bytes()))
+		}},
+
+		// Comparación y reempaquetado
+		{"diff", func(_ []Order, c []Certificate) {
+			DiffCertificates(c[:5], c[3:])
+			CertificateSetsEqual(c[:5], c[3:])
+		}},
+		{"repack", func(o []Order, c []Certificate) {
+			ConsolidateUnderfilled(c, limit, 0.6)
+			GreedyMinimize(c, limit)
+			SimulatePlacement(c, o[0], limit)
+			DissolveCertificate(c, 5, limit)
+			BalanceWithinVariance(c, limit, 10)
+			Merge(c[0], c[1])
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, c := cloneOrders(orders), cloneCertificates(certs)
+			tt.run(o, c)
+			if !reflect.DeepEqual(o, orders) {
+				t.Error("orders modified")
+			}
+			if !reflect.DeepEqual(c, certs) {
+				t.Error("certificates modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ShuffleOrders devuelve una copia de las órdenes mezclada de forma determinística
+// con la semilla indicada. La misma semilla produce siempre el mismo orden.
+func ShuffleOrders(orders []Order, seed int64) []Order {
+	shuffled := append([]Order(nil), orders...)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// FilterOrders devuelve, en un slice nuevo, las órdenes que cumplen todos los
+// predicados, conservando el orden original. Sin predicados devuelve una copia.
+// Para separar las descartadas basta con llamarla de nuevo con el predicado negado.
+func FilterOrders(orders []Order, predicates ...func(Order) bool) []Order {
+	var kept []Order
+next:
+	for _, order := range orders {
+		for _, keep := range predicates {
+			if !keep(order) {
+				continue next
+			}
+		}
+		kept = append(kept, order)
+	}
+	return kept
+}
+
+// ByMerchant devuelve un predicado para FilterOrders que conserva las órdenes
+// de los comerciantes indicados
+func ByMerchant(merchantIDs ...int) func(Order) bool {
+	wanted := make(map[int]bool, len(merchantIDs))
+	for _, id := range merchantIDs {
+		wanted[id] = true
+	}
+	return func(order Order) bool {
+		return wanted[order.MerchantID]
+	}
+}
+
+// AmountBetween devuelve un predicado para FilterOrders que conserva las órdenes
+// con monto entre low y high, ambos incluidos
+func AmountBetween(low, high float64) func(Order) bool {
+	return func(order Order) bool {
+		return order.Amount >= low && order.Amount <= high
+	}
+}
+
+// ExcludeOrders devuelve una copia de orders sin las órdenes cuyo ID está en
+// ids, por ejemplo las retenidas para revisión antes de empaquetar
+func ExcludeOrders(orders []Order, ids map[int]bool) []Order {
+	return FilterOrders(orders, func(order Order) bool {
+		return !ids[order.ID]
+	})
+}
+
+// AfterTime devuelve un predicado para FilterOrders que conserva las órdenes con
+// Timestamp posterior a t. Las órdenes sin Timestamp se descartan.
+func AfterTime(t time.Time) func(Order) bool {
+	return func(order Order) bool {
+		return !order.Timestamp.IsZero() && order.Timestamp.After(t)
+	}
+}
+
+// OnWeekday es un predicado para FilterOrders que descarta las órdenes de
+// sábado y domingo según la zona horaria de su Timestamp. Las órdenes sin
+// Timestamp se conservan, ya que no se sabe en qué día ocurrieron.
+func OnWeekday(order Order) bool {
+	if order.Timestamp.IsZero() {
+		return true
+	}
+	day := order.Timestamp.Weekday()
+	return day != time.Saturday && day != time.Sunday
+}
+
+// Generator genera órdenes como la simulación original pero reutilizando la
+// memoria entre ejecuciones, para servicios que corren muchas simulaciones y no
+// quieren reservar millones de órdenes cada vez
+type Generator struct {
+	opts GenOptions
+	buf  []Order
+}
+
+// NewGenerator crea un generador para opts, que se valida como en la simulación
+func NewGenerator(opts GenOptions) (*Generator, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{opts: opts}, nil
+}
+
+// Reset cambia la semilla de las próximas ejecuciones (0 = basada en la hora actual)
+func (g *Generator) Reset(seed int64) {
+	g.opts.Seed = seed
+}
+
+// Generate genera las órdenes sobre into, reutilizando su capacidad si alcanza,
+// y devuelve el slice resultante. Con into nil usa un buffer propio del
+// generador, que la siguiente llamada con nil sobrescribe: quien necesite
+// conservar un resultado debe copiarlo o pasar su propio slice.
+func (g *Generator) Generate(into []Order) []Order {
+	own := into == nil
+	if own {
+		into = g.buf
+	}
+	if total := g.opts.totalOrders(); cap(into) < total {
+		into = make([]Order, 0, total)
+	}
+	orders := fillOrders(into, g.opts)
+	if own {
+		g.buf = orders
+	}
+	return orders
+}
+
+// GenerateOrdersParallel genera ordersPerMerchant órdenes para cada uno de los
+// numMerchants comerciantes repartiendo los comerciantes entre workers
+// goroutines (workers < 1 usa GOMAXPROCS). Cada comerciante usa su propio
+// generador con una semilla derivada de seed y su ID, por lo que el resultado es
+// idéntico para cualquier cantidad de workers, aunque distinto del de
+// generateOrders con la misma semilla. Con seed 0 se usa la hora actual.
+func GenerateOrdersParallel(numMerchants, ordersPerMerchant int, seed int64, workers int) ([]Order, error) {
+	return GenerateOrdersParallelContext(context.Background(), numMerchants, ordersPerMerchant, seed, workers)
+}
+
+// GenerateOrdersParallelContext es como GenerateOrdersParallel pero todos los
+// workers se detienen en cuanto ctx termina, devolviendo su error
+func GenerateOrdersParallelContext(ctx context.Context, numMerchants, ordersPerMerchant int, seed int64, workers int) ([]Order, error) {
+	if numMerchants < 0 || ordersPerMerchant < 0 {
+		return nil, fmt.Errorf("configuración inválida: %d comerciantes, %d órdenes por comerciante",
+			numMerchants, ordersPerMerchant)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	// Cada comerciante escribe en su propio tramo del slice, sin sincronización
+	orders := make([]Order, numMerchants*ordersPerMerchant)
+	err := runShards(ctx, workers, func(ctx context.Context, w int) error {
+		for merchantID := w + 1; merchantID <= numMerchants; merchantID += workers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r := rand.New(rand.NewSource(merchantSeed(seed, merchantID)))
+			base := (merchantID - 1) * ordersPerMerchant
+			for j := 0; j < ordersPerMerchant; j++ {
+				// Mismo rango y redondeo que generateOrders
+				amount := 10.0 + r.Float64()*990.0
+				orders[base+j] = Order{
+					ID:         base + j + 1,
+					Amount:     float64(int(amount*100)) / 100,
+					MerchantID: merchantID,
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// runShards ejecuta fn para cada shard en 0..shards-1 en su propia goroutine,
+// como errgroup.WithContext: el primer error cancela el contexto compartido para
+// que los demás shards se detengan, y es el que se devuelve tras esperarlos.
+func runShards(ctx context.Context, shards int, fn func(ctx context.Context, shard int) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for shard := 0; shard < shards; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			if err := fn(ctx, shard); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel(err)
+				})
+			}
+		}(shard)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// merchantSeed deriva la semilla de un comerciante mezclando seed y su ID con
+// SplitMix64, para que comerciantes consecutivos no tengan secuencias parecidas
+func merchantSeed(seed int64, merchantID int) int64 {
+	z := uint64(seed) + uint64(merchantID)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestShuffleOrdersDeterministic(t *testing.T) {
+	var orders []Order
+	for i := range 50 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(1000 + 37*i%900), MerchantID: i % 4})
+	}
+	original := append([]Order(nil), orders...)
+
+	a := ShuffleOrders(orders, 42)
+	b := ShuffleOrders(orders, 42)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the same seed produced different shuffles")
+	}
+	if reflect.DeepEqual(a, ShuffleOrders(orders, 43)) {
+		t.Error("seeds 42 and 43 produced the same shuffle")
+	}
+	if !reflect.DeepEqual(orders, original) {
+		t.Error("ShuffleOrders modified its input")
+	}
+
+	// FFD ordena antes de empaquetar, así que la mezcla previa no cambia el resultado
+	opts := PackOptions{Limit: 5000}
+	layout := func(certs []Certificate) [][]int {
+		var ids [][]int
+		for _, cert := range certs {
+			var certIDs []int
+			for _, order := range cert.Orders {
+				certIDs = append(certIDs, order.ID)
+			}
+			ids = append(ids, certIDs)
+		}
+		return ids
+	}
+	base := layout(GenerateCertificates(orders, opts).Certificates)
+	for _, seed := range []int64{1, 42, 2024} {
+		shuffled := layout(GenerateCertificates(ShuffleOrders(orders, seed), opts).Certificates)
+		if !reflect.DeepEqual(base, shuffled) {
+			t.Errorf("FFD output changed after shuffling with seed %d", seed)
+		}
+	}
+}
+
+func TestOnWeekdayExcludesWeekendOrders(t *testing.T) {
+	buenosAires := time.FixedZone("ART", -3*60*60)
+	orders := []Order{
+		{ID: 1, Amount: 10, Timestamp: time.Date(2024, 5, 31, 12, 0, 0, 0, time.UTC)}, // Viernes
+		{ID: 2, Amount: 10, Timestamp: time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)},   // Sábado
+		{ID: 3, Amount: 10, Timestamp: time.Date(2024, 6, 2, 18, 0, 0, 0, time.UTC)},  // Domingo
+		{ID: 4, Amount: 10, Timestamp: time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)},   // Lunes
+		// Viernes 23:30 en Buenos Aires aunque en UTC ya sea sábado
+		{ID: 5, Amount: 10, Timestamp: time.Date(2024, 5, 31, 23, 30, 0, 0, buenosAires)},
+		{ID: 6, Amount: 10}, // Sin Timestamp
+	}
+
+	var got []int
+	for _, order := range FilterOrders(orders, OnWeekday) {
+		got = append(got, order.ID)
+	}
+	if want := []int{1, 4, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterOrders(OnWeekday) kept %v, want %v", got, want)
+	}
+}
+
+func TestFilterOrdersChainsPredicates(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 50, MerchantID: 1},
+		{ID: 2, Amount: 500, MerchantID: 1},
+		{ID: 3, Amount: 150, MerchantID: 2},
+		{ID: 4, Amount: 100, MerchantID: 3},
+		{ID: 5, Amount: 200, MerchantID: 1},
+		{ID: 6, Amount: 120, MerchantID: 3},
+	}
+
+	var got []int
+	for _, order := range FilterOrders(orders, ByMerchant(1, 3), AmountBetween(100, 200)) {
+		got = append(got, order.ID)
+	}
+	// Quedan solo las que cumplen ambos, en el orden original
+	if want := []int{4, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterOrders kept %v, want %v", got, want)
+	}
+
+	if all := FilterOrders(orders); !reflect.DeepEqual(all, orders) {
+		t.Errorf("FilterOrders without predicates = %v, want a copy of the input", all)
+	}
+}
+
+func TestGenerateOrdersParallelIndependentOfWorkers(t *testing.T) {
+	single, err := GenerateOrdersParallel(37, 25, 99, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(single) != 37*25 {
+		t.Fatalf("generated %d orders, want %d", len(single), 37*25)
+	}
+	for _, workers := range []int{4, 16} {
+		parallel, err := GenerateOrdersParallel(37, 25, 99, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parallel, single) {
+			t.Errorf("output with %d workers differs from the output with 1", workers)
+		}
+	}
+
+	other, err := GenerateOrdersParallel(37, 25, 100, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(other, single) {
+		t.Error("seeds 99 and 100 produced the same orders")
+	}
+}
+
+func TestRunShardsFirstErrorCancelsOthers(t *testing.T) {
+	errShard := errors.New("shard 2 falló")
+	done := make(chan error, 1)
+	go func() {
+		done <- runShards(context.Background(), 6, func(ctx context.Context, shard int) error {
+			if shard == 2 {
+				return errShard
+			}
+			// Los demás solo terminan si el error cancela el contexto compartido
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errShard) {
+			t.Errorf("runShards = %v, want the injected error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runShards hung after one shard failed")
+	}
+}
+
+func TestGenerateOrdersParallelContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	orders, err := GenerateOrdersParallelContext(ctx, 50, 100, 1, 4)
+	if !errors.Is(err, context.Canceled) || orders != nil {
+		t.Errorf("got %d orders and error %v, want no orders and context.Canceled", len(orders), err)
+	}
+}
+
+func TestGeneratorReusesBufferAcrossSeeds(t *testing.T) {
+	opts := GenOptions{Merchants: 12, OrdersPerMerchant: 40, Seed: 1}
+	gen, err := NewGenerator(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]Order, 0, 12*40)
+	first := gen.Generate(buf)
+	firstCopy := cloneOrders(first)
+
+	gen.Reset(2)
+	second := gen.Generate(buf)
+	if &second[0] != &buf[:1][0] {
+		t.Error("Generate allocated a new slice although the buffer had capacity")
+	}
+
+	// Cada resultado es el mismo que daría la simulación con esa semilla
+	for seed, got := range map[int64][]Order{1: firstCopy, 2: second} {
+		opts.Seed = seed
+		want, err := generateOrders(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("seed %d: Generate differs from generateOrders", seed)
+		}
+	}
+	if reflect.DeepEqual(firstCopy, second) {
+		t.Error("seeds 1 and 2 produced the same orders")
+	}
+
+	// Sin buffer propio, la siguiente llamada con nil reutiliza el del generador
+	own := gen.Generate(nil)
+	if again := gen.Generate(nil); &again[0] != &own[0] {
+		t.Error("Generate(nil) did not reuse the generator's buffer")
+	}
+}
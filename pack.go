@@ -0,0 +1,955 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sort"
+	"time"
+)
+
+// defaultCertificateLimit es el límite usado cuando PackOptions no indica uno
+const defaultCertificateLimit = 500000.0
+
+// Strategy identifica el algoritmo de empaquetado de GenerateCertificates
+type Strategy string
+
+const (
+	// StrategyFirstFitDecreasing es el algoritmo por defecto: First-Fit-Decreasing
+	// seguido de la fase de equilibrio para los últimos certificados
+	StrategyFirstFitDecreasing Strategy = "ffd"
+
+	// StrategyFirstFitIncreasing recorre las órdenes de menor a mayor monto con
+	// First-Fit. Suele producir más certificados que FFD porque las órdenes
+	// grandes llegan al final, cuando los certificados ya tienen poco espacio.
+	StrategyFirstFitIncreasing Strategy = "ffi"
+
+	// StrategyMaximizeFullCerts llena los certificados de a uno, agregando al
+	// actual cada orden restante que quepa, de mayor a menor, antes de abrir el
+	// siguiente. Busca la mayor cantidad de certificados llenos y deja el sobrante
+	// en el último, en lugar de repartirlo como la fase de equilibrio de FFD.
+	// Ordena siempre por monto, sin aplicar SortKey ni InputPreSorted.
+	StrategyMaximizeFullCerts Strategy = "full"
+)
+
+// defaultEpsilon es la tolerancia usada cuando PackOptions no indica una
+const defaultEpsilon = 1e-6
+
+// PackOptions configura el empaquetado de órdenes en certificados
+type PackOptions struct {
+	Limit          float64  // Monto máximo por certificado en todas las estrategias (0 = $500,000)
+	Strategy       Strategy // Algoritmo de empaquetado ("" = StrategyFirstFitDecreasing)
+	MinOrderAmount float64  // Las órdenes por debajo de este monto se rechazan (0 = sin mínimo)
+
+	// OverflowLimit permite reintentar las órdenes que exceden Limit por sí
+	// mismas empaquetándolas con este límite mayor, en certificados marcados
+	// como excepción (IsOverflow). 0 desactiva el reintento.
+	OverflowLimit float64
+
+	// Epsilon es la tolerancia de todas las comparaciones contra el límite, para
+	// que el error de redondeo al acumular montos (un certificado en
+	// límite+1e-9) no cuente como exceso. 0 = 1e-6.
+	Epsilon float64
+
+	// MainPhaseCertificates fija cuántos certificados puede abrir la fase de
+	// First-Fit-Decreasing antes de derivar órdenes a la fase de equilibrio, en
+	// lugar de estimarlo como ceil(total/límite) menos los reservados. 0 = automático.
+	MainPhaseCertificates int
+
+	// DisableBalancePhase empaqueta todas las órdenes con First-Fit-Decreasing,
+	// sin la fase de equilibrio que reparte las últimas en certificados de
+	// llenado parejo pero menor. Tiene prioridad sobre MainPhaseCertificates.
+	DisableBalancePhase bool
+
+	// InputPreSorted indica que las órdenes ya vienen de mayor a menor monto (o
+	// SortKey), desempatando por ID, por ejemplo desde una consulta ordenada,
+	// para que las estrategias decrecientes no las vuelvan a ordenar. El
+	// resultado es el mismo que ordenándolas; si no están ordenadas, el
+	// empaquetado es válido pero peor. Se ignora con ShuffleSeed, Groups o
+	// EffectiveAmount, que alteran el orden.
+	InputPreSorted bool
+
+	// KahanSummation acumula el monto de cada certificado con la suma
+	// compensada de Kahan en lugar de sumas sucesivas, lo que reduce el error
+	// de redondeo en certificados con miles de órdenes (ver
+	// MaxAccumulationDrift) a costa de algo de velocidad
+	KahanSummation bool
+
+	// ShuffleSeed mezcla las órdenes con esta semilla antes de empaquetar, para
+	// comprobar que el resultado no depende del orden de entrada. 0 = sin mezclar.
+	ShuffleSeed int64
+
+	// SortKey, si no es nil, reemplaza al monto como criterio del orden
+	// decreciente en que las estrategias recorren las órdenes (por ejemplo,
+	// monto por prioridad), desempatando por ID. En StrategyFirstFitIncreasing
+	// define el orden creciente. No cambia los montos que se comparan con los
+	// límites.
+	SortKey func(Order) float64
+
+	// MaxMerchantsPerCert limita la cantidad de comerciantes distintos por
+	// certificado: una orden de un comerciante nuevo solo se agrega si el
+	// certificado tiene menos comerciantes que este tope. 0 = sin tope.
+	MaxMerchantsPerCert int
+
+	// AllowPartial hace que GenerateCertificatesContext, si el contexto vence o
+	// se cancela antes de terminar, devuelva sin error los certificados armados
+	// hasta ese momento y las órdenes pendientes en Result.Remaining.
+	AllowPartial bool
+
+	// MerchantCohesion empaqueta comerciante por comerciante para que las órdenes
+	// de cada uno queden en el menor número de certificados posible, a costa de
+	// un llenado menor. SortMerchantsByTotal procesa primero a los comerciantes
+	// de mayor monto total, que así reciben los primeros certificados.
+	MerchantCohesion     bool
+	SortMerchantsByTotal bool
+
+	// SingleMerchantPerCert garantiza que cada certificado contenga órdenes de
+	// un único comerciante, aunque eso desperdicie capacidad. Tiene prioridad
+	// sobre MerchantCohesion.
+	SingleMerchantPerCert bool
+
+	// resumeFrom son los certificados de un checkpoint sobre los que continúa
+	// ResumeCertificates; nil en un empaquetado normal
+	resumeFrom []Certificate
+
+	// EffectiveAmount, si no es nil, calcula el monto neto de cada orden (por
+	// ejemplo, el monto menos una comisión). Limit, OverflowLimit, SoftLimit,
+	// MinOrderAmount y MinCertAmount se comparan contra el neto, pero las
+	// órdenes y los certificados del resultado conservan los montos brutos, por
+	// lo que Certificate.Amount puede superar Limit.
+	EffectiveAmount func(Order) float64
+
+	// SoftLimit es un monto que los certificados pueden superar (hasta Limit) a
+	// cambio de una penalización de PenaltyPerDollarOver por cada peso de
+	// exceso, informada en Result.OverflowPenalty. No cambia el empaquetado:
+	// solo lo califica. 0 desactiva la penalización.
+	SoftLimit            float64
+	PenaltyPerDollarOver float64
+
+	// IDStart es el ID del primer certificado, para que lotes sucesivos no
+	// repitan IDs. 0 = 1.
+	IDStart int
+
+	// OnCertificate, si no es nil, recibe cada certificado con su ID definitivo
+	// en lugar de acumularlo en Result.Certificates, que queda vacío. Con
+	// MerchantCohesion los certificados se entregan a medida que se cierran.
+	OnCertificate func(Certificate)
+
+	// ChunkMerchants hace que MerchantCohesion agrupe y empaquete los
+	// comerciantes en lotes de este tamaño en lugar de agrupar todas las órdenes
+	// a la vez, con el mismo resultado y menos memoria. Combinado con
+	// OnCertificate, los certificados cerrados se entregan antes de armar el
+	// lote siguiente. 0 = un único lote.
+	ChunkMerchants int
+
+	// MinCertAmount es el monto mínimo de un certificado. Los que quedan por
+	// debajo al terminar el empaquetado se disuelven y sus órdenes se juntan para
+	// formar certificados que lo alcancen o completar otros con espacio; las que
+	// aun así no llegan se devuelven en Result.BelowMinimum. Con OnCertificate
+	// los certificados se entregan recién al final. 0 = sin mínimo.
+	MinCertAmount float64
+
+	// Groups reúne órdenes que deben quedar en el mismo certificado, como una
+	// compra y su línea de impuestos: a cada ID de grupo le corresponden los IDs
+	// de sus órdenes. Cada grupo se empaqueta como una unidad con el monto
+	// combinado, que es el que se compara con los límites y MinOrderAmount; si
+	// no se coloca, todas sus órdenes terminan juntas en el mismo campo de
+	// Result. Para las restricciones por comerciante el grupo cuenta como del
+	// comerciante de su primera orden. Una orden no puede estar en dos grupos.
+	Groups map[int][]int
+
+	// AntiAffinity enumera pares de IDs de órdenes que nunca deben compartir
+	// certificado, por ejemplo por un conflicto de interés entre comerciantes:
+	// al colocar una orden se saltean los certificados que ya contienen su par.
+	AntiAffinity [][2]int
+
+	// PinnedCertificates son certificados ya emitidos que no deben cambiar al
+	// volver a empaquetar un conjunto modificado: sus órdenes se quitan de la
+	// entrada y sus IDs quedan reservados, así que los certificados nuevos se
+	// numeran desde el siguiente al mayor de ellos (o desde IDStart, si es
+	// mayor). Result.Certificates los incluye al comienzo tal como se
+	// recibieron; con OnCertificate no se entregan, porque ya fueron emitidos.
+	PinnedCertificates []Certificate
+
+	// Now, si no es nil, reemplaza a time.Now como reloj con el que
+	// GenerateCertificates registra en Certificate.CreatedAt el cierre de cada
+	// certificado, por ejemplo con un reloj fijo en pruebas
+	Now func() time.Time
+
+	// conflicts indexa AntiAffinity por ID de orden en ambos sentidos; lo arma
+	// packOrders y es nil si no hay pares
+	conflicts map[int][]int
+
+	// warnings acumula las advertencias del empaquetado para Result.Warnings; lo
+	// arma GenerateCertificatesContext
+	warnings *[]string
+
+	// streamed cuenta los certificados ya entregados a OnCertificate durante el
+	// empaquetado, para que los siguientes continúen la numeración
+	streamed *int
+}
+
+// Result agrupa los certificados generados y las órdenes que quedaron fuera
+type Result struct {
+	Certificates []Certificate
+	Rejected     []Order // Órdenes por debajo de MinOrderAmount, nunca empaquetadas
+	Unplaceable  []Order // Órdenes que exceden el límite aplicable y no se pudieron colocar
+
+	// Partial indica que el contexto terminó antes de completar el empaquetado;
+	// Remaining contiene las órdenes que no llegaron a procesarse
+	Partial   bool
+	Remaining []Order
+
+	// OverflowPenalty es la penalización total por superar opts.SoftLimit
+	OverflowPenalty float64
+
+	// BelowMinimum contiene las órdenes que no llegaron a formar un certificado
+	// de al menos opts.MinCertAmount
+	BelowMinimum []Order
+
+	// Warnings describe lo que el empaquetado tuvo que tolerar, como órdenes que
+	// exceden el límite por sí mismas o certificados que lo superan dentro de la
+	// tolerancia de Epsilon. El paquete no las imprime: quien llama decide si
+	// mostrarlas.
+	Warnings []string
+}
+
+// GenerateCertificates empaqueta las órdenes en certificados según las opciones.
+// Las órdenes por debajo de MinOrderAmount no se certifican y se devuelven en
+// Result.Rejected. Las que exceden el límite por sí mismas nunca se mezclan con
+// el resto: se reintentan con OverflowLimit si está configurado o se devuelven
+// en Result.Unplaceable. El slice de entrada no se modifica. Con opciones
+// inválidas o con órdenes que no pasan ValidateOrders el resultado queda vacío;
+// GenerateCertificatesContext devuelve el error.
+func GenerateCertificates(orders []Order, opts PackOptions) Result {
+	// Con un contexto que nunca termina el empaquetado no puede fallar
+	result, _ := GenerateCertificatesContext(context.Background(), orders, opts)
+	return result
+}
+
+// GenerateCertificatesContext es como GenerateCertificates pero deja de empaquetar
+// cuando ctx termina. Por defecto devuelve entonces el error del contexto; con
+// opts.AllowPartial devuelve en cambio el resultado parcial sin error, útil para
+// ejecuciones con un plazo máximo.
+func GenerateCertificatesContext(ctx context.Context, orders []Order, opts PackOptions) (Result, error) {
+	if err := ValidateOrders(orders); err != nil {
+		return Result{}, err
+	}
+	if len(opts.PinnedCertificates) > 0 {
+		return packPinned(ctx, orders, opts)
+	}
+	if opts.EffectiveAmount != nil {
+		return packNetAmounts(ctx, orders, opts)
+	}
+	if len(opts.Groups) > 0 {
+		return packGroups(ctx, orders, opts)
+	}
+
+	// Los algoritmos numeran desde 1; al reanudar se conservan los IDs del checkpoint
+	idShift := 0
+	if opts.IDStart > 1 && opts.resumeFrom == nil {
+		idShift = opts.IDStart - 1
+	}
+
+	// Los certificados se sellan al entregarse; los que ya tienen CreatedAt
+	// vienen de un checkpoint y conservan su momento de cierre
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	seal := func(cert *Certificate) {
+		if cert.CreatedAt.IsZero() {
+			cert.CreatedAt = now()
+		}
+	}
+
+	var warnings []string
+	opts.warnings = &warnings
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	checkLimit := func(cert Certificate) {
+		certLimit := limit
+		if cert.IsOverflow {
+			certLimit = opts.OverflowLimit
+		}
+		if cert.Amount > certLimit {
+			opts.warnf("certificado %d suma $%v, por encima del límite de $%.2f pero dentro de la tolerancia",
+				cert.ID, cert.Amount, certLimit)
+		}
+	}
+
+	// Los certificados que el algoritmo entrega mientras empaqueta reciben el
+	// mismo tratamiento que los devueltos al final
+	var penalty float64
+	deliver := opts.OnCertificate
+	if deliver != nil {
+		// Con MinCertAmount ningún certificado es definitivo hasta el final
+		if opts.MinCertAmount <= 0 {
+			streamed := 0
+			opts.streamed = &streamed
+		}
+		opts.OnCertificate = func(cert Certificate) {
+			cert.ID += idShift
+			seal(&cert)
+			checkLimit(cert)
+			penalty += SoftLimitPenalty([]Certificate{cert}, opts.SoftLimit, opts.PenaltyPerDollarOver)
+			deliver(cert)
+		}
+	}
+
+	result, err := packOrders(ctx, orders, opts)
+	if err != nil {
+		return result, err
+	}
+	if deliver != nil {
+		for _, cert := range result.Certificates {
+			opts.OnCertificate(cert)
+		}
+		result.Certificates = nil
+	} else {
+		penalty = SoftLimitPenalty(result.Certificates, opts.SoftLimit, opts.PenaltyPerDollarOver)
+		for i := range result.Certificates {
+			result.Certificates[i].ID += idShift
+			seal(&result.Certificates[i])
+			checkLimit(result.Certificates[i])
+		}
+	}
+	if opts.SoftLimit > 0 {
+		result.OverflowPenalty = penalty
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
+// packOrders aparta las órdenes rechazadas y excedidas, empaqueta el resto con
+// el algoritmo que corresponde a opts y reintenta las excedidas con OverflowLimit
+func packOrders(ctx context.Context, orders []Order, opts PackOptions) (Result, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	if opts.ShuffleSeed != 0 {
+		orders = ShuffleOrders(orders, opts.ShuffleSeed)
+	}
+
+	accepted := make([]Order, 0, len(orders))
+	var rejected, oversized []Order
+	for _, order := range orders {
+		if opts.MinOrderAmount > 0 && order.Amount < opts.MinOrderAmount {
+			rejected = append(rejected, order)
+			continue
+		}
+		// La comparación negada también aparta los montos NaN, que no caben en ningún límite
+		if !(order.Amount <= limitWithTolerance(limit, &opts)) {
+			opts.warnf("orden %d excede el límite por sí misma: $%.2f", order.ID, order.Amount)
+			oversized = append(oversized, order)
+			continue
+		}
+		accepted = append(accepted, order)
+	}
+
+	opts.Limit = limit
+	if len(opts.AntiAffinity) > 0 {
+		opts.conflicts = make(map[int][]int)
+		for _, pair := range opts.AntiAffinity {
+			opts.conflicts[pair[0]] = append(opts.conflicts[pair[0]], pair[1])
+			opts.conflicts[pair[1]] = append(opts.conflicts[pair[1]], pair[0])
+		}
+	}
+	pack := generateCertificates
+	switch {
+	case opts.resumeFrom != nil && (opts.Strategy == StrategyFirstFitIncreasing || opts.DisableBalancePhase):
+		pack = packResume
+	case opts.SingleMerchantPerCert:
+		pack = packSingleMerchant
+	case opts.MerchantCohesion:
+		pack = packByMerchant
+	case opts.Strategy == StrategyFirstFitIncreasing:
+		pack = packFirstFitIncreasing
+	case opts.Strategy == StrategyMaximizeFullCerts:
+		pack = packMaximizeFull
+	case opts.Strategy != "" && opts.Strategy != StrategyFirstFitDecreasing:
+		return Result{}, fmt.Errorf("estrategia de empaquetado desconocida: %q", opts.Strategy)
+	}
+	certificates, pending, err := pack(ctx, accepted, opts)
+	if err != nil {
+		if !opts.AllowPartial {
+			return Result{}, err
+		}
+		// Las órdenes excedidas quedan pendientes junto con el resto, sin reintentar
+		return Result{
+			Certificates: certificates,
+			Rejected:     rejected,
+			Partial:      true,
+			Remaining:    append(pending, oversized...),
+		}, nil
+	}
+
+	result := Result{
+		Certificates: certificates,
+		Rejected:     rejected,
+	}
+	if opts.MinCertAmount > 0 {
+		result.Certificates, result.BelowMinimum = poolBelowMinimum(certificates, opts)
+	}
+
+	if len(oversized) == 0 {
+		return result, nil
+	}
+	if opts.OverflowLimit <= limit {
+		result.Unplaceable = oversized
+		return result, nil
+	}
+
+	// Segundo intento: empaquetar las órdenes excedidas con el límite de excepción
+	var retry []Order
+	for _, order := range oversized {
+		if !(order.Amount <= limitWithTolerance(opts.OverflowLimit, &opts)) {
+			result.Unplaceable = append(result.Unplaceable, order)
+			continue
+		}
+		retry = append(retry, order)
+	}
+	overflowOpts := opts
+	overflowOpts.Limit = opts.OverflowLimit
+	overflow := firstFitDecreasing(retry, overflowOpts, len(result.Certificates)+streamedCount(&opts)+1)
+	for i := range overflow {
+		overflow[i].IsOverflow = true
+	}
+	result.Certificates = append(result.Certificates, overflow...)
+
+	return result, nil
+}
+
+// poolBelowMinimum disuelve los certificados por debajo de opts.MinCertAmount y
+// reempaqueta juntas sus órdenes, de mayor a menor, primero en el espacio libre
+// de los certificados que alcanzan el mínimo y luego en certificados nuevos. Así
+// las órdenes sobrantes de varios certificados chicos se acumulan hasta
+// alcanzarlo. Devuelve los certificados resultantes y las órdenes de los que
+// siguen por debajo del mínimo.
+func poolBelowMinimum(certificates []Certificate, opts PackOptions) ([]Certificate, []Order) {
+	// La misma tolerancia que contra el límite, para que el redondeo al acumular
+	// montos no deje por debajo un certificado que alcanza justo el mínimo
+	floor := opts.MinCertAmount - limitWithTolerance(0, &opts)
+	if opts.SingleMerchantPerCert {
+		opts.MaxMerchantsPerCert = 1
+	}
+
+	var kept []Certificate
+	var builders []certificateBuilder
+	var pooled []Order
+	for _, cert := range certificates {
+		if cert.Amount < floor {
+			pooled = append(pooled, cert.Orders...)
+			continue
+		}
+		builder := newCertificateBuilder(&opts)
+		for _, order := range cert.Orders {
+			builder.add(order)
+		}
+		builders = append(builders, builder)
+		kept = append(kept, cert)
+	}
+	if len(pooled) == 0 {
+		return certificates, nil
+	}
+	sort.Slice(pooled, func(i, j int) bool {
+		return amountDescending(pooled[i], pooled[j])
+	})
+
+	// Con un contexto que nunca termina no hay órdenes pendientes ni error
+	packed, _, _ := firstFitOrdered(context.Background(), builders, pooled, opts, 1)
+
+	result := make([]Certificate, 0, len(packed))
+	var below []Order
+	nextID := maxCertificateID(kept) + 1
+	for i, cert := range packed {
+		if cert.Amount < floor {
+			below = append(below, cert.Orders...)
+			continue
+		}
+		// Al reanudar se conservan los IDs del checkpoint; si no, se renumera
+		// para no dejar huecos donde estaban los certificados disueltos
+		switch {
+		case opts.resumeFrom == nil:
+			cert.ID = len(result) + 1
+		case i < len(kept):
+			cert.ID = kept[i].ID
+		default:
+			cert.ID = nextID
+			nextID++
+		}
+		result = append(result, cert)
+	}
+	return result, below
+}
+
+// packNetAmounts empaqueta copias de las órdenes con el monto neto de
+// opts.EffectiveAmount y luego restituye los montos brutos en el resultado. Así
+// los algoritmos de empaquetado no pagan el costo del hook cuando no se usa.
+func packNetAmounts(ctx context.Context, orders []Order, opts PackOptions) (Result, error) {
+	gross := make(map[int]float64, len(orders))
+	net := make([]Order, len(orders))
+	for i, order := range orders {
+		gross[order.ID] = order.Amount
+		net[i] = order
+		net[i].Amount = opts.EffectiveAmount(order)
+	}
+
+	restore := func(orders []Order) {
+		for i := range orders {
+			orders[i].Amount = gross[orders[i].ID]
+		}
+	}
+	restoreCertificate := func(cert *Certificate) {
+		restore(cert.Orders)
+		cert.Amount = 0
+		for _, order := range cert.Orders {
+			cert.Amount += order.Amount
+		}
+	}
+
+	opts.EffectiveAmount = nil
+	opts.InputPreSorted = false // El orden por monto bruto no vale para el neto
+	if deliver := opts.OnCertificate; deliver != nil {
+		opts.OnCertificate = func(cert Certificate) {
+			restoreCertificate(&cert)
+			deliver(cert)
+		}
+	}
+	result, err := GenerateCertificatesContext(ctx, net, opts)
+	if err != nil {
+		return result, err
+	}
+
+	for i := range result.Certificates {
+		restoreCertificate(&result.Certificates[i])
+	}
+	restore(result.Rejected)
+	restore(result.Unplaceable)
+	restore(result.Remaining)
+	restore(result.BelowMinimum)
+	return result, nil
+}
+
+// packGroups empaqueta cada grupo de opts.Groups como una única orden con el
+// monto combinado, que toma el ID y el comerciante de la primera orden del
+// grupo, y luego la reemplaza por las órdenes del grupo en el resultado
+func packGroups(ctx context.Context, orders []Order, opts PackOptions) (Result, error) {
+	groupOf := make(map[int]int, len(opts.Groups))
+	for groupID, ids := range opts.Groups {
+		for _, id := range ids {
+			if other, ok := groupOf[id]; ok && other != groupID {
+				return Result{}, fmt.Errorf("la orden %d pertenece a los grupos %d y %d", id, other, groupID)
+			}
+			groupOf[id] = groupID
+		}
+	}
+
+	members := make(map[int][]Order) // ID de la unidad -> órdenes del grupo
+	unitIndex := make(map[int]int)   // ID de grupo -> posición de su unidad
+	unitOf := make(map[int]int)      // ID de orden agrupada -> ID de su unidad
+	units := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		groupID, grouped := groupOf[order.ID]
+		if !grouped {
+			units = append(units, order)
+			continue
+		}
+		i, ok := unitIndex[groupID]
+		if !ok {
+			i = len(units)
+			unitIndex[groupID] = i
+			units = append(units, Order{ID: order.ID, MerchantID: order.MerchantID})
+		}
+		units[i].Amount += order.Amount
+		members[units[i].ID] = append(members[units[i].ID], order)
+		unitOf[order.ID] = units[i].ID
+	}
+
+	// Los pares incompatibles pasan a referirse a las unidades
+	if len(opts.AntiAffinity) > 0 {
+		pairs := make([][2]int, len(opts.AntiAffinity))
+		for i, pair := range opts.AntiAffinity {
+			pairs[i] = pair
+			for j, id := range pair {
+				if unit, ok := unitOf[id]; ok {
+					pairs[i][j] = unit
+				}
+			}
+			if pair[0] != pair[1] && pairs[i][0] == pairs[i][1] {
+				return Result{}, fmt.Errorf("las órdenes %d y %d no pueden compartir certificado pero están en el mismo grupo",
+					pair[0], pair[1])
+			}
+		}
+		opts.AntiAffinity = pairs
+	}
+
+	expand := func(orders []Order) []Order {
+		if orders == nil {
+			return nil
+		}
+		expanded := make([]Order, 0, len(orders))
+		for _, order := range orders {
+			if group, ok := members[order.ID]; ok {
+				expanded = append(expanded, group...)
+			} else {
+				expanded = append(expanded, order)
+			}
+		}
+		return expanded
+	}
+
+	opts.Groups = nil
+	opts.InputPreSorted = false // Las unidades suman montos: hay que reordenarlas
+	if deliver := opts.OnCertificate; deliver != nil {
+		opts.OnCertificate = func(cert Certificate) {
+			cert.Orders = expand(cert.Orders)
+			deliver(cert)
+		}
+	}
+	result, err := GenerateCertificatesContext(ctx, units, opts)
+	if err != nil {
+		return result, err
+	}
+
+	for i := range result.Certificates {
+		result.Certificates[i].Orders = expand(result.Certificates[i].Orders)
+	}
+	result.Rejected = expand(result.Rejected)
+	result.Unplaceable = expand(result.Unplaceable)
+	result.Remaining = expand(result.Remaining)
+	result.BelowMinimum = expand(result.BelowMinimum)
+	return result, nil
+}
+
+// packPinned empaqueta las órdenes que no están en opts.PinnedCertificates en
+// certificados nuevos con IDs posteriores a los fijados, y antepone estos al
+// resultado sin modificarlos
+func packPinned(ctx context.Context, orders []Order, opts PackOptions) (Result, error) {
+	pinned := opts.PinnedCertificates
+	pinnedIDs := make(map[int]bool)
+	for _, cert := range pinned {
+		for _, order := range cert.Orders {
+			pinnedIDs[order.ID] = true
+		}
+	}
+	free := make([]Order, 0, len(orders))
+	for _, order := range orders {
+		if !pinnedIDs[order.ID] {
+			free = append(free, order)
+		}
+	}
+
+	opts.PinnedCertificates = nil
+	opts.IDStart = max(opts.IDStart, maxCertificateID(pinned)+1)
+	result, err := GenerateCertificatesContext(ctx, free, opts)
+	if err != nil {
+		return result, err
+	}
+	if opts.OnCertificate == nil {
+		result.Certificates = append(append([]Certificate(nil), pinned...), result.Certificates...)
+	}
+	return result, nil
+}
+
+// streamedCount devuelve cuántos certificados ya se entregaron a OnCertificate
+// durante el empaquetado
+func streamedCount(opts *PackOptions) int {
+	if opts.streamed == nil {
+		return 0
+	}
+	return *opts.streamed
+}
+
+// warnf registra una advertencia para Result.Warnings, si hay dónde hacerlo
+func (opts *PackOptions) warnf(format string, args ...any) {
+	if opts.warnings != nil {
+		*opts.warnings = append(*opts.warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// limitWithTolerance devuelve el monto máximo admitido para limit según opts.Epsilon
+func limitWithTolerance(limit float64, opts *PackOptions) float64 {
+	if opts.Epsilon == 0 {
+		return limit + defaultEpsilon
+	}
+	return limit + opts.Epsilon
+}
+
+// amountDescending ordena de mayor a menor monto, desempatando por ID para que
+// el resultado no dependa del orden de entrada
+func amountDescending(a, b Order) bool {
+	if a.Amount != b.Amount {
+		return a.Amount > b.Amount
+	}
+	return a.ID < b.ID
+}
+
+// orderDescending ordena de mayor a menor según opts.SortKey, o por monto si es
+// nil, desempatando por ID como amountDescending
+func orderDescending(a, b Order, opts *PackOptions) bool {
+	if opts.SortKey == nil {
+		return amountDescending(a, b)
+	}
+	if ka, kb := opts.SortKey(a), opts.SortKey(b); ka != kb {
+		return ka > kb
+	}
+	return a.ID < b.ID
+}
+
+// sortDescending ordena orders en el lugar según orderDescending, salvo que
+// opts.InputPreSorted indique que ya lo están
+func sortDescending(orders []Order, opts *PackOptions) {
+	if opts.InputPreSorted && opts.ShuffleSeed == 0 {
+		return
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orderDescending(orders[i], orders[j], opts)
+	})
+}
+
+// firstFitDecreasing empaqueta una copia de las órdenes ordenada de mayor a menor
+// monto, colocando cada una en el primer certificado con espacio según opts.Limit
+// y las restricciones de opts. Los IDs de los certificados comienzan en firstID.
+func firstFitDecreasing(orders []Order, opts PackOptions, firstID int) []Certificate {
+	sorted := append([]Order(nil), orders...)
+	sortDescending(sorted, &opts)
+
+	// Con un contexto que nunca termina no hay órdenes pendientes ni error
+	certificates, _, _ := firstFitOrdered(context.Background(), nil, sorted, opts, firstID)
+	return certificates
+}
+
+// packFirstFitIncreasing empaqueta con First-Fit recorriendo las órdenes de menor
+// a mayor monto (ver StrategyFirstFitIncreasing)
+func packFirstFitIncreasing(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return orderDescending(sorted[j], sorted[i], &opts)
+	})
+	return firstFitOrdered(ctx, nil, sorted, opts, 1)
+}
+
+// packMaximizeFull llena los certificados de a uno (ver
+// StrategyMaximizeFullCerts). Cada certificado empieza con la mayor orden sin
+// colocar y recorre las restantes de mayor a menor, saltando con una búsqueda
+// binaria las que exceden el espacio libre.
+func packMaximizeFull(ctx context.Context, orders []Order, opts PackOptions) ([]Certificate, []Order, error) {
+	// La búsqueda binaria necesita el orden por monto, así que no se aplican
+	// SortKey ni InputPreSorted
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return amountDescending(sorted[i], sorted[j])
+	})
+	maxAmount := limitWithTolerance(opts.Limit, &opts)
+
+	// next[i] lleva a la primera orden sin colocar desde i (len(sorted) si no
+	// queda ninguna); se comprime al recorrerlo para no volver a saltar las
+	// órdenes ya colocadas
+	next := make([]int, len(sorted)+1)
+	for i := range next {
+		next[i] = i
+	}
+	find := func(i int) int {
+		for next[i] != i {
+			next[i] = next[next[i]]
+			i = next[i]
+		}
+		return i
+	}
+
+	var certificates []Certificate
+	for first := find(0); first < len(sorted); first = find(0) {
+		if err := ctx.Err(); err != nil {
+			var pending []Order
+			for i := first; i < len(sorted); i = find(i + 1) {
+				pending = append(pending, sorted[i])
+			}
+			return certificates, pending, err
+		}
+
+		builder := newCertificateBuilder(&opts)
+		builder.add(sorted[first])
+		next[first] = first + 1
+		for i := find(first + 1); i < len(sorted); i = find(i + 1) {
+			free := maxAmount - builder.Amount
+			if sorted[i].Amount > free {
+				// Las órdenes siguientes que tampoco caben son un prefijo: saltarlas juntas
+				j := sort.Search(len(sorted), func(k int) bool {
+					return sorted[k].Amount <= free
+				})
+				if j >= len(sorted) {
+					break
+				}
+				if i = find(j); i >= len(sorted) {
+					break
+				}
+			}
+			if builder.fits(sorted[i], maxAmount, &opts) {
+				builder.add(sorted[i])
+				next[i] = i + 1
+			}
+		}
+
+		certificates = append(certificates, Certificate{
+			ID:     len(certificates) + 1,
+			Amount: builder.Amount,
+			Orders: builder.Orders,
+		})
+	}
+	return certificates, nil, nil
+}
+
+// firstFitOrdered coloca cada orden, en el orden recibido, en el primer
+// certificado con espacio según opts.Limit y las restricciones de opts, abriendo
+// uno nuevo cuando no cabe en ninguno. builders son certificados ya abiertos que
+// se prueban primero. Los IDs comienzan en firstID. Si ctx termina antes,
+// devuelve lo armado, las órdenes pendientes y el error.
+func firstFitOrdered(ctx context.Context, builders []certificateBuilder, orders []Order, opts PackOptions, firstID int) ([]Certificate, []Order, error) {
+	maxAmount := limitWithTolerance(opts.Limit, &opts)
+	var pending []Order
+	var ctxErr error
+	for idx, order := range orders {
+		if idx%contextCheckInterval == 0 {
+			if ctxErr = ctx.Err(); ctxErr != nil {
+				pending = orders[idx:]
+				break
+			}
+		}
+
+		placed := false
+		for i := range builders {
+			if builders[i].fits(order, maxAmount, &opts) {
+				builders[i].add(order)
+				placed = true
+				break
+			}
+		}
+
+		if !placed {
+			builder := newCertificateBuilder(&opts)
+			builder.add(order)
+			builders = append(builders, builder)
+		}
+	}
+
+	certificates := make([]Certificate, len(builders))
+	for i, builder := range builders {
+		certificates[i] = Certificate{
+			ID:     firstID + i,
+			Amount: builder.Amount,
+			Orders: builder.Orders,
+		}
+	}
+	return certificates, pending, ctxErr
+}
+
+// GenerateCertificatesSeq empaqueta órdenes que llegan como un iterador, por
+// ejemplo desde un cursor de base de datos, sin materializar el slice completo.
+// Como un flujo no puede ordenarse de antemano, usa First-Fit en el orden de
+// llegada (sin el ordenamiento decreciente de GenerateCertificates), por lo que
+// el llenado suele ser algo peor que con el slice completo. Las órdenes que por
+// sí solas exceden el límite no se colocan: se devuelven en Result.Unplaceable,
+// con una advertencia en Result.Warnings.
+func GenerateCertificatesSeq(orders iter.Seq[Order], limit float64) Result {
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	maxAmount := limitWithTolerance(limit, &PackOptions{})
+
+	var result Result
+	for order := range orders {
+		if !(order.Amount <= maxAmount) {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("orden %d excede el límite por sí misma: $%.2f", order.ID, order.Amount))
+			result.Unplaceable = append(result.Unplaceable, order)
+			continue
+		}
+
+		placed := false
+		for i := range result.Certificates {
+			if result.Certificates[i].Amount+order.Amount <= maxAmount {
+				result.Certificates[i].Orders = append(result.Certificates[i].Orders, order)
+				result.Certificates[i].Amount += order.Amount
+				placed = true
+				break
+			}
+		}
+
+		if !placed {
+			result.Certificates = append(result.Certificates, Certificate{
+				ID:     len(result.Certificates) + 1,
+				Amount: order.Amount,
+				Orders: []Order{order},
+			})
+		}
+	}
+
+	return result
+}
+
+// BalancePack ejecuta solo la fase de equilibrio de GenerateCertificates sobre
+// las órdenes dadas, como si fueran las que sobraron de la fase principal:
+// las reparte de mayor a menor monto en alrededor de reservedCount certificados
+// de monto parejo sin superar limit. Sirve para probar y reutilizar esa
+// heurística por separado. Con reservedCount < 1 se usa 1 y con limit <= 0 el
+// límite por defecto.
+func BalancePack(remainingOrders []Order, reservedCount int, limit float64) []Certificate {
+	if len(remainingOrders) == 0 {
+		return nil
+	}
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	reservedCount = max(reservedCount, 1)
+
+	sorted := append([]Order(nil), remainingOrders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return amountDescending(sorted[i], sorted[j])
+	})
+	certificates, _, _ := balancePhase(context.Background(), sorted, reservedCount, limit, PackOptions{Limit: limit}, 1)
+	return certificates
+}
+
+// bestFitDecreasing empaqueta una copia de las órdenes ordenada de mayor a menor
+// monto, colocando cada una en el certificado que quede con menos espacio libre
+// tras agregarla. Los IDs de los certificados comienzan en firstID.
+func bestFitDecreasing(orders []Order, opts PackOptions, firstID int) []Certificate {
+	sorted := append([]Order(nil), orders...)
+	sortDescending(sorted, &opts)
+
+	maxAmount := limitWithTolerance(opts.Limit, &opts)
+	var builders []certificateBuilder
+	for _, order := range sorted {
+		best := -1
+		for i := range builders {
+			if !builders[i].fits(order, maxAmount, &opts) {
+				continue
+			}
+			if best < 0 || builders[i].Amount > builders[best].Amount {
+				best = i
+			}
+		}
+
+		if best >= 0 {
+			builders[best].add(order)
+			continue
+		}
+		builder := newCertificateBuilder(&opts)
+		builder.add(order)
+		builders = append(builders, builder)
+	}
+
+	certificates := make([]Certificate, len(builders))
+	for i, builder := range builders {
+		certificates[i] = Certificate{
+			ID:     firstID + i,
+			Amount: builder.Amount,
+			Orders: builder.Orders,
+		}
+	}
+	return certificates
+}
@@ -0,0 +1,873 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMinOrderAmountRejectsBelowFloor(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 500, MerchantID: 1},
+		{ID: 2, Amount: 9.99, MerchantID: 1},
+		{ID: 3, Amount: 10, MerchantID: 2},
+		{ID: 4, Amount: 0.5, MerchantID: 3},
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: 1000, MinOrderAmount: 10})
+
+	rejected := make(map[int]bool)
+	for _, order := range result.Rejected {
+		rejected[order.ID] = true
+	}
+	if len(rejected) != 2 || !rejected[2] || !rejected[4] {
+		t.Fatalf("Rejected = %v, want orders 2 and 4", result.Rejected)
+	}
+	placed := make(map[int]bool)
+	for _, cert := range result.Certificates {
+		for _, order := range cert.Orders {
+			if rejected[order.ID] {
+				t.Errorf("rejected order %d packed into certificate %d", order.ID, cert.ID)
+			}
+			placed[order.ID] = true
+		}
+	}
+	// Las demás, incluida la que está justo en el mínimo, se certifican
+	if !placed[1] || !placed[3] {
+		t.Errorf("orders at or above the floor missing from the certificates: placed %v", placed)
+	}
+}
+
+func TestGenerateCertificatesSeqFromSliceIterator(t *testing.T) {
+	const limit = 500
+	orders := []Order{
+		{ID: 1, Amount: 200, MerchantID: 1},
+		{ID: 2, Amount: 600, MerchantID: 1}, // Excede el límite por sí sola
+		{ID: 3, Amount: 350, MerchantID: 2},
+		{ID: 4, Amount: 300, MerchantID: 3},
+		{ID: 5, Amount: 150, MerchantID: 2},
+		{ID: 6, Amount: 100, MerchantID: 1},
+	}
+
+	result := GenerateCertificatesSeq(slices.Values(orders), limit)
+
+	for _, cert := range result.Certificates {
+		if cert.Amount > limit {
+			t.Errorf("certificate %d holds %.2f, over the limit %d", cert.ID, cert.Amount, limit)
+		}
+	}
+	if len(result.Unplaceable) != 1 || result.Unplaceable[0].ID != 2 {
+		t.Errorf("Unplaceable = %v, want only order 2", result.Unplaceable)
+	}
+	// First-Fit en orden de llegada: 200+300 | 350+150 | 100
+	if got := len(result.Certificates); got != 3 {
+		t.Errorf("got %d certificates, want 3", got)
+	}
+	placeable := slices.DeleteFunc(slices.Clone(orders), func(o Order) bool { return o.ID == 2 })
+	if missing := MissingOrders(placeable, result.Certificates); len(missing) != 0 {
+		t.Errorf("orders missing from the certificates: %v", missing)
+	}
+}
+
+func TestOverflowLimitRetriesOversizedOrders(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 400, MerchantID: 1},
+		{ID: 2, Amount: 700, MerchantID: 2}, // Supera el límite normal de 500
+		{ID: 3, Amount: 100, MerchantID: 1},
+	}
+
+	without := GenerateCertificates(orders, PackOptions{Limit: 500})
+	if len(without.Unplaceable) != 1 || without.Unplaceable[0].ID != 2 {
+		t.Fatalf("without OverflowLimit: Unplaceable = %v, want order 2", without.Unplaceable)
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: 500, OverflowLimit: 800})
+	if len(result.Unplaceable) != 0 {
+		t.Errorf("Unplaceable = %v, want none with OverflowLimit 800", result.Unplaceable)
+	}
+	placed := 0
+	for _, cert := range result.Certificates {
+		placed += len(cert.Orders)
+		hasOversized := slices.ContainsFunc(cert.Orders, func(o Order) bool { return o.ID == 2 })
+		if hasOversized != cert.IsOverflow {
+			t.Errorf("certificate %d: IsOverflow = %v, holds order 2 = %v", cert.ID, cert.IsOverflow, hasOversized)
+		}
+		if !cert.IsOverflow && cert.Amount > 500 {
+			t.Errorf("normal certificate %d holds %.2f, over the limit", cert.ID, cert.Amount)
+		}
+	}
+	if placed != len(orders) {
+		t.Errorf("%d orders placed, want all %d", placed, len(orders))
+	}
+
+	// Si ni el límite de excepción alcanza, la orden sigue sin colocarse
+	tooLow := GenerateCertificates(orders, PackOptions{Limit: 500, OverflowLimit: 600})
+	if len(tooLow.Unplaceable) != 1 || tooLow.Unplaceable[0].ID != 2 {
+		t.Errorf("with OverflowLimit 600: Unplaceable = %v, want order 2", tooLow.Unplaceable)
+	}
+}
+
+func TestMaxMerchantsPerCertCapsDistinctMerchants(t *testing.T) {
+	// Muchas órdenes chicas de seis comercios que, sin el tope, cabrían en un solo certificado
+	var orders []Order
+	for i := range 36 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(10 + i), MerchantID: i%6 + 1})
+	}
+
+	unconstrained := GenerateCertificates(orders, PackOptions{Limit: 100000})
+	if got := len(unconstrained.Certificates); got != 1 {
+		t.Fatalf("without a cap: got %d certificates, want 1", got)
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: 100000, MaxMerchantsPerCert: 2})
+	placed := 0
+	for _, cert := range result.Certificates {
+		placed += len(cert.Orders)
+		merchants := make(map[int]bool)
+		for _, order := range cert.Orders {
+			merchants[order.MerchantID] = true
+		}
+		if len(merchants) > 2 {
+			t.Errorf("certificate %d holds orders from %d merchants, want at most 2", cert.ID, len(merchants))
+		}
+	}
+	if placed != len(orders) {
+		t.Errorf("%d orders placed, want all %d", placed, len(orders))
+	}
+}
+
+// fuzzOrders decodifica data como una secuencia de montos float64 de 8 bytes
+func fuzzOrders(data []byte) []Order {
+	orders := make([]Order, 0, len(data)/8)
+	for i := 0; i+8 <= len(data); i += 8 {
+		amount := math.Float64frombits(binary.LittleEndian.Uint64(data[i:]))
+		orders = append(orders, Order{ID: len(orders) + 1, Amount: amount, MerchantID: len(orders)%4 + 1})
+	}
+	return orders
+}
+
+// fuzzAmounts codifica montos en el formato que lee fuzzOrders
+func fuzzAmounts(amounts ...float64) []byte {
+	data := make([]byte, 0, 8*len(amounts))
+	for _, amount := range amounts {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(amount))
+	}
+	return data
+}
+
+func FuzzGenerateCertificates(f *testing.F) {
+	f.Add(fuzzAmounts(400, 350.5, 120.25, 80), 500.0, uint8(0))
+	f.Add(fuzzAmounts(250, 250, 250, 250, 0.1, 0.2), 500.0, uint8(1))
+	f.Add(fuzzAmounts(600, 10, 499.99), 500.0, uint8(2))
+	f.Add(fuzzAmounts(1e6, 0.01, 300000), 0.0, uint8(3))
+	// Un monto negativo enorme desbordaba la estimación de certificados y make() entraba en pánico
+	f.Add(fuzzAmounts(-1e300, 100, 200), 500.0, uint8(0))
+	f.Add(fuzzAmounts(math.NaN(), math.Inf(1), 50), 500.0, uint8(0))
+
+	f.Fuzz(func(t *testing.T, data []byte, limit float64, mode uint8) {
+		if math.IsNaN(limit) || math.IsInf(limit, 0) {
+			t.Skip()
+		}
+		orders := fuzzOrders(data)
+		opts := PackOptions{Limit: limit}
+		switch mode % 4 {
+		case 1:
+			opts.Strategy = StrategyFirstFitIncreasing
+		case 2:
+			opts.Strategy = StrategyMaximizeFullCerts
+		case 3:
+			opts.MerchantCohesion = true
+		}
+
+		result := GenerateCertificates(orders, opts)
+
+		effective := limit
+		if effective <= 0 {
+			effective = defaultCertificateLimit
+		}
+		seen := make(map[int]int)
+		for _, cert := range result.Certificates {
+			if cert.Amount > limitWithTolerance(effective, &opts) {
+				t.Errorf("certificate %d holds %v, over the limit %v", cert.ID, cert.Amount, effective)
+			}
+			for _, order := range cert.Orders {
+				seen[order.ID]++
+			}
+		}
+		for _, order := range append(result.Rejected, result.Unplaceable...) {
+			seen[order.ID]++
+		}
+		if len(seen) != len(orders) {
+			t.Fatalf("%d of %d orders accounted for", len(seen), len(orders))
+		}
+		for id, n := range seen {
+			if n != 1 {
+				t.Errorf("order %d appears %d times", id, n)
+			}
+		}
+	})
+}
+
+// countdownContext simula un plazo que vence en un punto exacto del
+// empaquetado: Err devuelve nil las primeras calls veces y err a partir de ahí
+type countdownContext struct {
+	context.Context
+	calls int
+	err   error
+}
+
+func newCountdownContext(calls int, err error) *countdownContext {
+	return &countdownContext{Context: context.Background(), calls: calls, err: err}
+}
+
+func (c *countdownContext) Err() error {
+	if c.calls == 0 {
+		return c.err
+	}
+	c.calls--
+	return nil
+}
+
+func TestAllowPartialReturnsCompletedWorkOnDeadline(t *testing.T) {
+	var orders []Order
+	for i := range 3 * contextCheckInterval {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i%900), MerchantID: i%50 + 1})
+	}
+	// El plazo vence en la tercera revisión, tras procesar dos bloques de órdenes
+	ctx := newCountdownContext(2, context.DeadlineExceeded)
+
+	result, err := GenerateCertificatesContext(ctx, orders, PackOptions{Limit: 5000, AllowPartial: true})
+	if err != nil {
+		t.Fatalf("AllowPartial returned error %v, want a partial result", err)
+	}
+	if !result.Partial {
+		t.Fatal("Partial = false after the deadline")
+	}
+	if len(result.Certificates) == 0 || len(result.Remaining) == 0 {
+		t.Fatalf("got %d certificates and %d remaining orders, want both non-empty",
+			len(result.Certificates), len(result.Remaining))
+	}
+	// Cada orden está certificada o pendiente, nunca ambas ni ninguna
+	seen := make(map[int]int)
+	for _, cert := range result.Certificates {
+		if cert.Amount > 5000 {
+			t.Errorf("certificate %d holds %.2f, over the limit", cert.ID, cert.Amount)
+		}
+		for _, order := range cert.Orders {
+			seen[order.ID]++
+		}
+	}
+	for _, order := range result.Remaining {
+		seen[order.ID]++
+	}
+	for _, order := range orders {
+		if seen[order.ID] != 1 {
+			t.Errorf("order %d appears %d times among certified and remaining, want once", order.ID, seen[order.ID])
+		}
+	}
+
+	// Sin AllowPartial el mismo plazo es un error
+	ctx = newCountdownContext(2, context.DeadlineExceeded)
+	if _, err := GenerateCertificatesContext(ctx, orders, PackOptions{Limit: 5000}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("without AllowPartial: err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestGenerateCertificatesSeqToleratesFloatNoise(t *testing.T) {
+	orders := []Order{{ID: 1, Amount: 0.1}, {ID: 2, Amount: 0.2}, {ID: 3, Amount: 0.30001}}
+	result := GenerateCertificatesSeq(slices.Values(orders), 0.3)
+	if len(result.Certificates) != 1 || len(result.Certificates[0].Orders) != 2 {
+		t.Errorf("got %+v, want orders 1 and 2 in a single certificate", result.Certificates)
+	}
+	if len(result.Unplaceable) != 1 || result.Unplaceable[0].ID != 3 {
+		t.Errorf("Unplaceable = %v, want order 3", result.Unplaceable)
+	}
+}
+
+func TestGenerateCertificatesEpsilonAtLimit(t *testing.T) {
+	// 0.2 + 0.1 suma 0.30000000000000004: apenas por encima del límite por ruido de punto flotante
+	orders := []Order{{ID: 1, Amount: 0.2, MerchantID: 1}, {ID: 2, Amount: 0.1, MerchantID: 1}}
+	for _, strategy := range []Strategy{StrategyFirstFitDecreasing, StrategyFirstFitIncreasing} {
+		result := GenerateCertificates(orders, PackOptions{Limit: 0.3, Strategy: strategy})
+		if len(result.Certificates) != 1 {
+			t.Errorf("%s: got %d certificates, want both orders in one", strategy, len(result.Certificates))
+		}
+		if err := VerifyCertificates(orders, result.Certificates, 0.3); err != nil {
+			t.Errorf("%s: VerifyCertificates: %v", strategy, err)
+		}
+	}
+
+	// Un exceso mayor que Epsilon abre otro certificado
+	orders = append(orders, Order{ID: 3, Amount: 0.001, MerchantID: 1})
+	result := GenerateCertificates(orders, PackOptions{Limit: 0.3, Epsilon: 1e-4})
+	if len(result.Certificates) != 2 {
+		t.Errorf("got %d certificates, want 2 when the overflow exceeds Epsilon", len(result.Certificates))
+	}
+}
+
+func TestFirstFitIncreasingSortsAscending(t *testing.T) {
+	// FFD arma 6+4 y 6+4; en orden ascendente los 4 se juntan y cada 6 queda solo
+	orders := []Order{
+		{ID: 1, Amount: 6, MerchantID: 1},
+		{ID: 2, Amount: 4, MerchantID: 2},
+		{ID: 3, Amount: 6, MerchantID: 3},
+		{ID: 4, Amount: 4, MerchantID: 4},
+	}
+
+	ffi := GenerateCertificates(orders, PackOptions{Limit: 10, Strategy: StrategyFirstFitIncreasing})
+	ffd := GenerateCertificates(orders, PackOptions{Limit: 10})
+
+	if len(ffi.Certificates) != 3 || len(ffd.Certificates) != 2 {
+		t.Fatalf("got %d FFI and %d FFD certificates, want 3 and 2", len(ffi.Certificates), len(ffd.Certificates))
+	}
+	first := ffi.Certificates[0]
+	if first.ID != 1 || len(first.Orders) != 2 || first.Orders[0].Amount != 4 || first.Orders[1].Amount != 4 {
+		t.Errorf("first FFI certificate = %+v, want the two smallest orders 2 and 4", first)
+	}
+	for _, cert := range ffi.Certificates {
+		if !slices.IsSortedFunc(cert.Orders, func(a, b Order) int { return cmp.Compare(a.Amount, b.Amount) }) {
+			t.Errorf("certificate %d orders not ascending: %+v", cert.ID, cert.Orders)
+		}
+	}
+	if err := VerifyCertificates(orders, ffi.Certificates, 10); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestEffectiveAmountAppliesLimitToNet(t *testing.T) {
+	const fee = 10.0
+	// Netas de 100: las tres caben en 300 aunque el bruto sume 330
+	orders := []Order{
+		{ID: 1, Amount: 110, MerchantID: 1},
+		{ID: 2, Amount: 110, MerchantID: 2},
+		{ID: 3, Amount: 110, MerchantID: 3},
+	}
+	net := func(order Order) float64 { return order.Amount - fee }
+
+	if gross := GenerateCertificates(orders, PackOptions{Limit: 300}); len(gross.Certificates) != 2 {
+		t.Fatalf("without the hook: got %d certificates, want 2", len(gross.Certificates))
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: 300, EffectiveAmount: net})
+	if len(result.Certificates) != 1 {
+		t.Fatalf("got %d certificates, want 1 with the net-based limit", len(result.Certificates))
+	}
+	cert := result.Certificates[0]
+	if cert.Amount != 330 {
+		t.Errorf("certificate Amount = %.2f, want the gross total 330", cert.Amount)
+	}
+	var netTotal float64
+	for _, order := range cert.Orders {
+		if order.Amount != 110 {
+			t.Errorf("order %d reports %.2f, want its gross amount 110", order.ID, order.Amount)
+		}
+		netTotal += net(order)
+	}
+	if netTotal > 300 {
+		t.Errorf("net total %.2f exceeds the limit", netTotal)
+	}
+}
+
+func TestSoftLimitReportsOverflowPenalty(t *testing.T) {
+	// FFD arma 700+300 = 1000, que supera el blando en 200, y deja 250 sola
+	orders := []Order{
+		{ID: 1, Amount: 700, MerchantID: 1},
+		{ID: 2, Amount: 250, MerchantID: 2},
+		{ID: 3, Amount: 300, MerchantID: 3},
+	}
+	result := GenerateCertificates(orders, PackOptions{
+		Limit:                1000,
+		SoftLimit:            800,
+		PenaltyPerDollarOver: 0.25,
+	})
+
+	over := 0
+	var overage float64
+	for _, cert := range result.Certificates {
+		if cert.Amount > 1000 {
+			t.Errorf("certificate %d holds %.2f, over the hard limit", cert.ID, cert.Amount)
+		}
+		if cert.Amount > 800 {
+			over++
+			overage += cert.Amount - 800
+		}
+	}
+	if over != 1 {
+		t.Fatalf("%d certificates exceed the soft limit, want 1: %+v", over, result.Certificates)
+	}
+	if overage != 200 || result.OverflowPenalty != 50 {
+		t.Errorf("overage %v, OverflowPenalty = %v; want 200 over and a penalty of 50", overage, result.OverflowPenalty)
+	}
+}
+
+func TestIDStartNumbersFromOffset(t *testing.T) {
+	var orders []Order
+	for i := range 30 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*31%400), MerchantID: i%4 + 1})
+	}
+
+	for _, opts := range []PackOptions{
+		{Limit: 1000, IDStart: 1000},
+		{Limit: 1000, IDStart: 1000, Strategy: StrategyFirstFitIncreasing},
+		{Limit: 1000, IDStart: 1000, MerchantCohesion: true},
+	} {
+		result := GenerateCertificates(orders, opts)
+		if len(result.Certificates) < 2 {
+			t.Fatalf("got %d certificates, want several", len(result.Certificates))
+		}
+		for i, cert := range result.Certificates {
+			if want := 1000 + i; cert.ID != want {
+				t.Errorf("%+v: certificate %d has ID %d, want %d", opts, i, cert.ID, want)
+			}
+		}
+	}
+
+	// Sin IDStart se numera desde 1
+	if first := GenerateCertificates(orders, PackOptions{Limit: 1000}).Certificates[0].ID; first != 1 {
+		t.Errorf("default first ID = %d, want 1", first)
+	}
+}
+
+func TestMinCertAmountPoolsSmallLeftovers(t *testing.T) {
+	const limit, floor = 1000, 500
+	// Con cohesión cada comerciante que no entra completo cierra el certificado
+	// actual: quedan 300 | 950 | 250, y los dos chicos no llegan al mínimo solos
+	orders := []Order{
+		{ID: 1, Amount: 300, MerchantID: 1},
+		{ID: 2, Amount: 950, MerchantID: 2},
+		{ID: 3, Amount: 150, MerchantID: 3},
+		{ID: 4, Amount: 100, MerchantID: 3},
+	}
+	opts := PackOptions{Limit: limit, MerchantCohesion: true}
+	if got := GenerateCertificates(orders, opts); len(got.Certificates) != 3 {
+		t.Fatalf("without a minimum got %d certificates, want 3 to pool from", len(got.Certificates))
+	}
+
+	opts.MinCertAmount = floor
+	result := GenerateCertificates(orders, opts)
+	if len(result.BelowMinimum) != 0 {
+		t.Errorf("BelowMinimum = %+v, want none", result.BelowMinimum)
+	}
+	if len(result.Certificates) != 2 {
+		t.Fatalf("got %d certificates, want 950 and the pooled 550", len(result.Certificates))
+	}
+	for _, cert := range result.Certificates {
+		if cert.Amount < floor {
+			t.Errorf("certificate %d holds %.2f, below the %d floor", cert.ID, cert.Amount, floor)
+		}
+	}
+	if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestMinCertAmountReportsOrdersThatNeverReachIt(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 800, MerchantID: 1},
+		{ID: 2, Amount: 700, MerchantID: 2},
+		{ID: 3, Amount: 250, MerchantID: 3},
+		{ID: 4, Amount: 150, MerchantID: 4},
+		{ID: 5, Amount: 100, MerchantID: 5},
+	}
+	// 800+150 y 700+250 no dejan lugar para la de 100, que sola no llega a 900
+	result := GenerateCertificates(orders, PackOptions{Limit: 1000, MinCertAmount: 900})
+	if len(result.BelowMinimum) != 1 || result.BelowMinimum[0].ID != 5 {
+		t.Errorf("BelowMinimum = %+v, want only order 5", result.BelowMinimum)
+	}
+	if err := VerifyCertificates(orders[:4], result.Certificates, 1000); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+	for _, cert := range result.Certificates {
+		if cert.Amount < 900 {
+			t.Errorf("certificate %d holds %.2f, below the floor", cert.ID, cert.Amount)
+		}
+	}
+}
+
+func TestCreatedAtFromInjectedClock(t *testing.T) {
+	var orders []Order
+	for i := range 60 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*37%400), MerchantID: i%6 + 1})
+	}
+	// Reloj fijo que avanza un segundo en cada lectura
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ticks := 0
+	clock := func() time.Time {
+		ticks++
+		return start.Add(time.Duration(ticks) * time.Second)
+	}
+
+	var delivered []Certificate
+	GenerateCertificates(orders, PackOptions{
+		Limit:         1000,
+		Now:           clock,
+		OnCertificate: func(cert Certificate) { delivered = append(delivered, cert) },
+	})
+	if len(delivered) < 2 {
+		t.Fatalf("got %d certificates, want several to compare", len(delivered))
+	}
+	if ticks != len(delivered) {
+		t.Errorf("clock read %d times for %d certificates, want once each", ticks, len(delivered))
+	}
+	for i, cert := range delivered {
+		if cert.CreatedAt.IsZero() {
+			t.Fatalf("certificate %d has no CreatedAt", cert.ID)
+		}
+		if i > 0 && !cert.CreatedAt.After(delivered[i-1].CreatedAt) {
+			t.Errorf("certificate %d sealed at %v, not after the previous one at %v",
+				cert.ID, cert.CreatedAt, delivered[i-1].CreatedAt)
+		}
+	}
+}
+
+func TestGroupsKeepOrdersTogether(t *testing.T) {
+	const limit = 1000
+	// Sin grupos FFD arma 600+350+50 | 500 y separa la compra 2 de su impuesto 5
+	orders := []Order{
+		{ID: 1, Amount: 600, MerchantID: 1},
+		{ID: 2, Amount: 500, MerchantID: 2},
+		{ID: 3, Amount: 350, MerchantID: 3},
+		{ID: 5, Amount: 50, MerchantID: 2},
+	}
+	sameCert := func(certs []Certificate, a, b int) bool {
+		certOf := make(map[int]int)
+		for _, cert := range certs {
+			for _, order := range cert.Orders {
+				certOf[order.ID] = cert.ID
+			}
+		}
+		return certOf[a] == certOf[b]
+	}
+	if ungrouped := GenerateCertificates(orders, PackOptions{Limit: limit}); sameCert(ungrouped.Certificates, 2, 5) {
+		t.Fatal("orders 2 and 5 share a certificate even without the group")
+	}
+
+	opts := PackOptions{Limit: limit, Groups: map[int][]int{7: {2, 5}}}
+	result := GenerateCertificates(orders, opts)
+	if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+		t.Fatalf("VerifyCertificates: %v", err)
+	}
+	if !sameCert(result.Certificates, 2, 5) {
+		t.Error("group orders 2 and 5 landed in different certificates")
+	}
+
+	// Juntas suman 1100: ninguna de las dos se coloca
+	orders[3].Amount = 600
+	result = GenerateCertificates(orders, opts)
+	for _, cert := range result.Certificates {
+		for _, order := range cert.Orders {
+			if order.ID == 2 || order.ID == 5 {
+				t.Errorf("order %d of an oversized group was placed in certificate %d", order.ID, cert.ID)
+			}
+		}
+	}
+	var unplaced []int
+	for _, order := range result.Unplaceable {
+		unplaced = append(unplaced, order.ID)
+	}
+	slices.Sort(unplaced)
+	if !slices.Equal(unplaced, []int{2, 5}) {
+		t.Errorf("Unplaceable = %v, want both group orders [2 5]", unplaced)
+	}
+}
+
+func TestAntiAffinitySeparatesForbiddenPair(t *testing.T) {
+	const limit = 1000
+	// Las tres entran en un solo certificado, pero 1 y 3 no pueden compartirlo
+	orders := []Order{
+		{ID: 1, Amount: 400, MerchantID: 1},
+		{ID: 2, Amount: 300, MerchantID: 2},
+		{ID: 3, Amount: 200, MerchantID: 3},
+	}
+	opts := PackOptions{Limit: limit, DisableBalancePhase: true}
+	if got := GenerateCertificates(orders, opts); len(got.Certificates) != 1 {
+		t.Fatalf("without constraints got %d certificates, want 1", len(got.Certificates))
+	}
+
+	for _, pair := range [][2]int{{1, 3}, {3, 1}} { // El orden del par no importa
+		opts.AntiAffinity = [][2]int{pair}
+		result := GenerateCertificates(orders, opts)
+		if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+			t.Fatalf("VerifyCertificates: %v", err)
+		}
+		for _, cert := range result.Certificates {
+			ids := make(map[int]bool)
+			for _, order := range cert.Orders {
+				ids[order.ID] = true
+			}
+			if ids[1] && ids[3] {
+				t.Errorf("pair %v: orders 1 and 3 share certificate %d", pair, cert.ID)
+			}
+		}
+		if len(result.Certificates) != 2 {
+			t.Errorf("pair %v: got %d certificates, want 2", pair, len(result.Certificates))
+		}
+	}
+}
+
+func TestBalancePackKnownLeftovers(t *testing.T) {
+	// repeated arma n órdenes de amount con IDs consecutivos
+	repeated := func(n int, amount float64) []Order {
+		var orders []Order
+		for i := range n {
+			orders = append(orders, Order{ID: i + 1, Amount: amount, MerchantID: i%3 + 1})
+		}
+		return orders
+	}
+
+	tests := []struct {
+		name     string
+		orders   []Order
+		reserved int
+		limit    float64
+		want     []float64 // Montos esperados de los certificados
+	}{
+		// Objetivo 500 por certificado: cada uno se cierra al alcanzarlo
+		{"target below limit", repeated(10, 100), 2, 1000, []float64{500, 500}},
+		// Objetivo igual al límite: se llenan hasta no admitir otra orden
+		{"target at limit", repeated(12, 250), 3, 1000, []float64{1000, 1000, 1000}},
+		// Más monto que lo que caben en los reservados: se abren los necesarios
+		{"more than reserved", repeated(9, 400), 2, 1000, []float64{800, 800, 800, 800, 400}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs := BalancePack(tt.orders, tt.reserved, tt.limit)
+			var got []float64
+			for i, cert := range certs {
+				if cert.ID != i+1 {
+					t.Errorf("certificate %d has ID %d, want IDs from 1", i, cert.ID)
+				}
+				got = append(got, cert.Amount)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("certificate amounts %v, want %v", got, tt.want)
+			}
+			if err := VerifyCertificates(tt.orders, certs, tt.limit); err != nil {
+				t.Errorf("VerifyCertificates: %v", err)
+			}
+		})
+	}
+}
+
+func TestSortKeyDefinesPackingOrder(t *testing.T) {
+	priority := map[int]float64{1: 1, 2: 3, 3: 2, 4: 3, 5: 0.5}
+	orders := []Order{
+		{ID: 1, Amount: 900, MerchantID: 1},
+		{ID: 2, Amount: 100, MerchantID: 2},
+		{ID: 3, Amount: 300, MerchantID: 3},
+		{ID: 4, Amount: 150, MerchantID: 4},
+		{ID: 5, Amount: 1000, MerchantID: 5},
+	}
+	// Con un solo certificado las órdenes quedan en el orden en que se recorrieron
+	packingOrder := func(key func(Order) float64) []int {
+		result := GenerateCertificates(orders, PackOptions{Limit: 10000, DisableBalancePhase: true, SortKey: key})
+		if len(result.Certificates) != 1 {
+			t.Fatalf("got %d certificates, want 1", len(result.Certificates))
+		}
+		var ids []int
+		for _, order := range result.Certificates[0].Orders {
+			ids = append(ids, order.ID)
+		}
+		return ids
+	}
+
+	if got, want := packingOrder(nil), []int{5, 1, 3, 4, 2}; !slices.Equal(got, want) {
+		t.Errorf("default order = %v, want by amount %v", got, want)
+	}
+	// Monto por prioridad de las órdenes 1 a 5: 900, 300, 600, 450 y 500
+	byWeighted := func(o Order) float64 { return o.Amount * priority[o.ID] }
+	if got, want := packingOrder(byWeighted), []int{1, 3, 5, 4, 2}; !slices.Equal(got, want) {
+		t.Errorf("amount×priority order = %v, want %v", got, want)
+	}
+	// Solo prioridad, con empate entre 2 y 4 resuelto por ID
+	byPriority := func(o Order) float64 { return priority[o.ID] }
+	if got, want := packingOrder(byPriority), []int{2, 4, 3, 1, 5}; !slices.Equal(got, want) {
+		t.Errorf("priority order = %v, want %v", got, want)
+	}
+}
+
+func TestWarningsCollectedInResult(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 400, MerchantID: 1},
+		{ID: 2, Amount: 1500, MerchantID: 2}, // Excede el límite por sí sola
+		{ID: 3, Amount: 300, MerchantID: 3},
+	}
+	// Las advertencias no deben imprimirse: se captura la salida estándar
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	var results []Result
+	for _, strategy := range []Strategy{StrategyFirstFitDecreasing, StrategyFirstFitIncreasing, StrategyMaximizeFullCerts} {
+		results = append(results, GenerateCertificates(orders, PackOptions{Limit: 1000, Strategy: strategy}))
+	}
+	results = append(results, GenerateCertificatesSeq(slices.Values(orders), 1000))
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if len(printed) > 0 {
+		t.Errorf("packing printed %q, want warnings only in the Result", printed)
+	}
+
+	for i, result := range results {
+		found := false
+		for _, warning := range result.Warnings {
+			if strings.Contains(warning, "orden 2 excede el límite") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("run %d: Warnings = %q, want one about order 2 exceeding the limit", i, result.Warnings)
+		}
+		if len(result.Unplaceable) != 1 || result.Unplaceable[0].ID != 2 {
+			t.Errorf("run %d: Unplaceable = %+v, want order 2", i, result.Unplaceable)
+		}
+	}
+
+	// También los certificados que pasan el límite por redondeo
+	result := GenerateCertificates([]Order{{ID: 1, Amount: 0.2, MerchantID: 1}, {ID: 2, Amount: 0.1, MerchantID: 1}},
+		PackOptions{Limit: 0.3})
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "dentro de la tolerancia") {
+		t.Errorf("Warnings = %q, want one about the certificate within tolerance", result.Warnings)
+	}
+}
+
+func TestPinnedCertificatesStayUntouched(t *testing.T) {
+	const limit = 1000
+	var orders []Order
+	for i := range 30 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*41%300), MerchantID: i%4 + 1})
+	}
+	// Ya emitidos, con IDs salteados y uno con espacio libre que no debe usarse
+	pinned := []Certificate{
+		{ID: 3, Amount: orders[0].Amount + orders[7].Amount, Orders: []Order{orders[0], orders[7]}},
+		{ID: 8, Amount: orders[12].Amount, Orders: []Order{orders[12]}},
+	}
+	before := cloneCertificates(pinned)
+
+	result := GenerateCertificates(orders, PackOptions{Limit: limit, PinnedCertificates: pinned})
+	if err := VerifyCertificates(orders, result.Certificates, limit); err != nil {
+		t.Fatalf("VerifyCertificates: %v", err)
+	}
+	if len(result.Certificates) <= len(pinned) {
+		t.Fatalf("got %d certificates, want new ones after the %d pinned", len(result.Certificates), len(pinned))
+	}
+	if got := result.Certificates[:len(pinned)]; DigestCertificates(got) != DigestCertificates(before) {
+		t.Errorf("pinned certificates changed: got %+v, want %+v", got, before)
+	}
+	// Los nuevos se numeran a continuación del mayor ID fijado
+	for i, cert := range result.Certificates[len(pinned):] {
+		if cert.ID != 9+i {
+			t.Errorf("new certificate %d has ID %d, want %d", i, cert.ID, 9+i)
+		}
+	}
+}
+
+func TestKahanSummationCloserToExactTotal(t *testing.T) {
+	// Montos de 1 a 9 centavos: el total exacto se calcula en centavos enteros
+	orders := make([]Order, 100000)
+	var cents int64
+	for i := range orders {
+		c := int64(1 + i%9)
+		orders[i] = Order{ID: i + 1, Amount: float64(c) / 100, MerchantID: i%30 + 1}
+		cents += c
+	}
+	exact := float64(cents) / 100
+
+	total := func(kahan bool) float64 {
+		result := GenerateCertificates(orders, PackOptions{Limit: 2000, KahanSummation: kahan})
+		var sum float64
+		for _, cert := range result.Certificates {
+			sum += cert.Amount
+		}
+		return sum
+	}
+	naiveErr := math.Abs(total(false) - exact)
+	kahanErr := math.Abs(total(true) - exact)
+	if naiveErr == 0 {
+		t.Fatal("naive summation is exact on this set; the comparison would prove nothing")
+	}
+	if kahanErr >= naiveErr {
+		t.Errorf("error with Kahan %g, want below the naive %g", kahanErr, naiveErr)
+	}
+}
+
+func TestInputPreSortedMatchesSortingPath(t *testing.T) {
+	var orders []Order
+	for i := range 3000 {
+		// Muchos montos repetidos, para que el desempate por ID importe
+		orders = append(orders, Order{ID: i + 1, Amount: float64(100 + i*7919%40*25), MerchantID: i%35 + 1})
+	}
+	byAmount := slices.Clone(orders)
+	slices.SortFunc(byAmount, func(a, b Order) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.ID, b.ID))
+	})
+	priority := func(o Order) float64 { return float64(o.MerchantID%7) * o.Amount }
+	byKey := slices.Clone(orders)
+	slices.SortFunc(byKey, func(a, b Order) int {
+		return cmp.Or(cmp.Compare(priority(b), priority(a)), cmp.Compare(a.ID, b.ID))
+	})
+
+	tests := []struct {
+		name   string
+		sorted []Order
+		opts   PackOptions
+	}{
+		{"ffd", byAmount, PackOptions{Limit: 20000}},
+		{"no-balance", byAmount, PackOptions{Limit: 20000, DisableBalancePhase: true}},
+		{"full", byAmount, PackOptions{Limit: 20000, Strategy: StrategyMaximizeFullCerts}},
+		{"max-merchants", byAmount, PackOptions{Limit: 20000, MaxMerchantsPerCert: 3}},
+		{"sort-key", byKey, PackOptions{Limit: 20000, SortKey: priority}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := GenerateCertificates(tt.sorted, tt.opts)
+			tt.opts.InputPreSorted = true
+			got := GenerateCertificates(tt.sorted, tt.opts)
+			if DigestCertificates(got.Certificates) != DigestCertificates(want.Certificates) {
+				t.Fatalf("got %d certificates, different from the %d of the sorting path",
+					len(got.Certificates), len(want.Certificates))
+			}
+		})
+	}
+}
+
+func TestMaximizeFullCertsMoreFullThanFFD(t *testing.T) {
+	const limit = 10000
+	// Montos dispares entre $50 y $3,000, como los de un lote real
+	orders := make([]Order, 400)
+	for i := range orders {
+		orders[i] = Order{ID: i + 1, Amount: float64(50 + i*7919%2951), MerchantID: i%40 + 1}
+	}
+
+	full := func(certs []Certificate) int {
+		n := 0
+		for _, cert := range certs {
+			if cert.Amount >= 0.99*limit {
+				n++
+			}
+		}
+		return n
+	}
+	ffd := GenerateCertificates(orders, PackOptions{Limit: limit}).Certificates
+	maxFull := GenerateCertificates(orders, PackOptions{Limit: limit, Strategy: StrategyMaximizeFullCerts}).Certificates
+	if err := VerifyCertificates(orders, maxFull, limit); err != nil {
+		t.Fatalf("VerifyCertificates: %v", err)
+	}
+	if got, base := full(maxFull), full(ffd); got <= base {
+		t.Errorf("%d certificates at 99%% or more, want more than FFD's %d", got, base)
+	}
+	// Con montos tan chicos frente al límite, solo el último queda con el resto
+	if got := full(maxFull[:len(maxFull)-1]); got != len(maxFull)-1 {
+		t.Errorf("%d of the first %d certificates at 99%% or more, want all", got, len(maxFull)-1)
+	}
+}
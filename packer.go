@@ -0,0 +1,73 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Packer atiende pedidos de empaquetado con una cantidad fija de workers, para
+// que un servidor que empaqueta a demanda no lance una goroutine por pedido.
+// Es seguro usarlo desde varias goroutines.
+type Packer struct {
+	jobs chan packJob
+	wg   sync.WaitGroup
+
+	mu     sync.RWMutex // Evita enviar a jobs mientras Close lo cierra
+	closed bool
+}
+
+// packJob es un pedido pendiente de Packer
+type packJob struct {
+	orders []Order
+	opts   PackOptions
+	result chan Result
+}
+
+// NewPacker inicia un Packer con workers goroutines (workers < 1 usa
+// GOMAXPROCS). La cola admite otros tantos pedidos en espera; cuando está
+// llena, Submit se bloquea hasta que un worker se libera.
+func NewPacker(workers int) *Packer {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	p := &Packer{jobs: make(chan packJob, workers)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for job := range p.jobs {
+				job.result <- GenerateCertificates(job.orders, job.opts)
+				close(job.result)
+			}
+		}()
+	}
+	return p
+}
+
+// Submit encola el empaquetado de orders con opts y devuelve un canal que
+// recibe el Result, como el de GenerateCertificates, y luego se cierra. orders
+// no debe modificarse hasta recibir el resultado. Tras Close el canal se
+// devuelve cerrado, sin resultado.
+func (p *Packer) Submit(orders []Order, opts PackOptions) <-chan Result {
+	result := make(chan Result, 1)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		close(result)
+		return result
+	}
+	p.jobs <- packJob{orders: orders, opts: opts, result: result}
+	return result
+}
+
+// Close deja de aceptar pedidos y espera a que terminen los ya encolados. Puede
+// llamarse más de una vez.
+func (p *Packer) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
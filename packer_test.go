@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPackerConcurrentJobsIndependent(t *testing.T) {
+	p := NewPacker(3)
+	defer p.Close()
+
+	// Cada pedido con sus propias órdenes y límite, para notar si se mezclan
+	type job struct {
+		orders []Order
+		limit  float64
+	}
+	jobs := make([]job, 12)
+	for j := range jobs {
+		jobs[j].limit = float64(800 + 100*j)
+		for i := range 50 + 10*j {
+			jobs[j].orders = append(jobs[j].orders, Order{
+				ID:         j*1000 + i + 1,
+				Amount:     float64(20 + (i*37+j*11)%400),
+				MerchantID: i%(j+2) + 1,
+			})
+		}
+	}
+
+	results := make([]Result, len(jobs))
+	var wg sync.WaitGroup
+	for j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[j] = <-p.Submit(jobs[j].orders, PackOptions{Limit: jobs[j].limit})
+		}()
+	}
+	wg.Wait()
+
+	for j, job := range jobs {
+		if err := VerifyCertificates(job.orders, results[j].Certificates, job.limit); err != nil {
+			t.Errorf("job %d: VerifyCertificates: %v", j, err)
+			continue
+		}
+		want := GenerateCertificates(job.orders, PackOptions{Limit: job.limit})
+		if DigestCertificates(results[j].Certificates) != DigestCertificates(want.Certificates) {
+			t.Errorf("job %d: the pool packed differently from a direct call", j)
+		}
+	}
+}
+
+func TestPackerCloseFinishesQueuedJobs(t *testing.T) {
+	p := NewPacker(1)
+	orders := []Order{{ID: 1, Amount: 300, MerchantID: 1}, {ID: 2, Amount: 500, MerchantID: 2}}
+
+	var pending []<-chan Result
+	for range 4 {
+		pending = append(pending, p.Submit(orders, PackOptions{Limit: 1000}))
+	}
+	p.Close()
+	p.Close() // Cerrar dos veces no falla
+
+	for i, ch := range pending {
+		result, ok := <-ch
+		if !ok || len(result.Certificates) != 1 {
+			t.Errorf("job %d submitted before Close: ok %v, %d certificates; want its result", i, ok, len(result.Certificates))
+		}
+	}
+	if _, ok := <-p.Submit(orders, PackOptions{Limit: 1000}); ok {
+		t.Error("Submit after Close delivered a result, want a closed channel")
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ConsolidateUnderfilled disuelve los certificados cuyo llenado (Amount/limit)
+// es menor que threshold y vuelve a empaquetar sus órdenes con Best-Fit-Decreasing.
+// Los certificados bien llenos se devuelven sin cambios; los nuevos reciben IDs a
+// continuación del mayor ID existente. Si la consolidación no reduce la cantidad
+// de certificados, se devuelve el conjunto original. Con limit <= 0 se usa el
+// límite por defecto.
+func ConsolidateUnderfilled(certs []Certificate, limit, threshold float64) []Certificate {
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	var kept, underfilled []Certificate
+	var pooled []Order
+	for _, cert := range certs {
+		if cert.Amount/limit < threshold {
+			underfilled = append(underfilled, cert)
+			pooled = append(pooled, cert.Orders...)
+			continue
+		}
+		kept = append(kept, cert)
+	}
+	if len(underfilled) < 2 {
+		return certs
+	}
+
+	repacked := bestFitDecreasing(pooled, PackOptions{Limit: limit}, maxCertificateID(certs)+1)
+	if len(repacked) >= len(underfilled) {
+		return certs
+	}
+
+	return append(kept, repacked...)
+}
+
+// maxCertificateID devuelve el mayor ID del conjunto, o 0 si está vacío
+func maxCertificateID(certs []Certificate) int {
+	maxID := 0
+	for _, cert := range certs {
+		if cert.ID > maxID {
+			maxID = cert.ID
+		}
+	}
+	return maxID
+}
+
+// CanMerge indica si los certificados a y b pueden unirse en uno solo sin que el
+// monto combinado exceda limit (con la misma tolerancia que VerifyCertificates)
+func CanMerge(a, b Certificate, limit float64) bool {
+	return a.Amount+b.Amount <= limit+defaultEpsilon
+}
+
+// Merge une los certificados a y b en uno nuevo con el ID de a, las órdenes de
+// ambos y la suma de sus montos. No comprueba el límite: quien llama debe
+// verificarlo antes con CanMerge.
+func Merge(a, b Certificate) Certificate {
+	orders := make([]Order, 0, len(a.Orders)+len(b.Orders))
+	orders = append(orders, a.Orders...)
+	orders = append(orders, b.Orders...)
+	return Certificate{
+		ID:         a.ID,
+		Amount:     a.Amount + b.Amount,
+		Orders:     orders,
+		IsOverflow: a.IsOverflow || b.IsOverflow,
+	}
+}
+
+// GreedyMinimize intenta eliminar certificados moviendo órdenes entre ellos.
+// Toma los certificados de menor a mayor monto y, para cada uno, mueve sus
+// órdenes de menor a mayor al certificado más lleno que todavía tenga espacio;
+// si todas encuentran lugar el certificado se elimina, y si no, se deshacen los
+// movimientos de ese certificado. Cada certificado se intenta vaciar una sola
+// vez, lo que acota el trabajo. Respeta el Limit propio de cada certificado y no
+// toca los marcados como IsOverflow. certs no se modifica.
+func GreedyMinimize(certs []Certificate, limit float64) []Certificate {
+	work := make([]Certificate, len(certs))
+	for i, cert := range certs {
+		work[i] = cert
+		work[i].Orders = append([]Order(nil), cert.Orders...)
+	}
+	maxAmount := func(cert *Certificate) float64 {
+		if cert.Limit > 0 {
+			return cert.Limit + defaultEpsilon
+		}
+		return limit + defaultEpsilon
+	}
+
+	candidates := make([]int, 0, len(work))
+	for i := range work {
+		if !work[i].IsOverflow {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return work[candidates[a]].Amount < work[candidates[b]].Amount
+	})
+
+	removed := make([]bool, len(work))
+	for _, src := range candidates {
+		orders := append([]Order(nil), work[src].Orders...)
+		sort.Slice(orders, func(i, j int) bool {
+			return amountDescending(orders[j], orders[i])
+		})
+
+		type move struct {
+			dst        int
+			prevAmount float64 // Para deshacer sin acumular error de redondeo
+		}
+		var moves []move
+		for _, order := range orders {
+			best := -1
+			for _, dst := range candidates {
+				if dst == src || removed[dst] || work[dst].Amount+order.Amount > maxAmount(&work[dst]) {
+					continue
+				}
+				if best < 0 || work[dst].Amount > work[best].Amount {
+					best = dst
+				}
+			}
+			if best < 0 {
+				break
+			}
+			moves = append(moves, move{best, work[best].Amount})
+			work[best].Orders = append(work[best].Orders, order)
+			work[best].Amount += order.Amount
+		}
+
+		if len(moves) == len(orders) {
+			removed[src] = true
+			continue
+		}
+		// No se pudo vaciar: deshacer en orden inverso
+		for i := len(moves) - 1; i >= 0; i-- {
+			dst := &work[moves[i].dst]
+			dst.Orders = dst.Orders[:len(dst.Orders)-1]
+			dst.Amount = moves[i].prevAmount
+		}
+	}
+
+	result := make([]Certificate, 0, len(work))
+	for i, cert := range work {
+		if !removed[i] {
+			result = append(result, cert)
+		}
+	}
+	return result
+}
+
+// SimulatePlacement indica dónde colocaría First-Fit una orden nueva sin
+// modificar certs: el ID del primer certificado, en el orden del slice, con
+// espacio para ella según limit (o su propio Limit), o bien newCert true y el ID
+// que recibiría el certificado nuevo. Los certificados IsOverflow no reciben
+// órdenes nuevas.
+func SimulatePlacement(certs []Certificate, order Order, limit float64) (certID int, newCert bool) {
+	for _, cert := range certs {
+		if cert.IsOverflow {
+			continue
+		}
+		certLimit := limit
+		if cert.Limit > 0 {
+			certLimit = cert.Limit
+		}
+		if cert.Amount+order.Amount <= certLimit+defaultEpsilon {
+			return cert.ID, false
+		}
+	}
+	return maxCertificateID(certs) + 1, true
+}
+
+// DissolveCertificate quita el certificado con el ID indicado y coloca sus
+// órdenes, de mayor a menor, en los restantes con First-Fit, con las mismas
+// reglas que SimulatePlacement. Devuelve los certificados resultantes y las
+// órdenes que no cupieron en ninguno, para que quien llama decida qué hacer con
+// ellas. Si no hay un certificado con ese ID, devuelve certs sin cambios.
+// certs no se modifica.
+func DissolveCertificate(certs []Certificate, id int, limit float64) ([]Certificate, []Order) {
+	dissolved := -1
+	for i, cert := range certs {
+		if cert.ID == id {
+			dissolved = i
+			break
+		}
+	}
+	if dissolved < 0 {
+		return certs, nil
+	}
+
+	result := make([]Certificate, 0, len(certs)-1)
+	result = append(result, certs[:dissolved]...)
+	result = append(result, certs[dissolved+1:]...)
+
+	orders := append([]Order(nil), certs[dissolved].Orders...)
+	sort.Slice(orders, func(i, j int) bool {
+		return amountDescending(orders[i], orders[j])
+	})
+
+	var leftover []Order
+	copied := make([]bool, len(result)) // Para copiar las órdenes de cada destino una sola vez
+	for _, order := range orders {
+		placed := false
+		for i := range result {
+			cert := &result[i]
+			if cert.IsOverflow {
+				continue
+			}
+			certLimit := limit
+			if cert.Limit > 0 {
+				certLimit = cert.Limit
+			}
+			if cert.Amount+order.Amount > certLimit+defaultEpsilon {
+				continue
+			}
+			if !copied[i] {
+				cert.Orders = append([]Order(nil), cert.Orders...)
+				copied[i] = true
+			}
+			cert.Orders = append(cert.Orders, order)
+			cert.Amount += order.Amount
+			placed = true
+			break
+		}
+		if !placed {
+			leftover = append(leftover, order)
+		}
+	}
+	return result, leftover
+}
+
+// BalanceWithinVariance redistribuye órdenes para que ningún certificado se
+// aparte de la media de montos más de maxVariancePct por ciento. En cada paso
+// toma el certificado más alejado de la media y mueve una orden, o intercambia
+// dos, con el certificado más alejado del lado opuesto con el que sea posible
+// (ver rebalancePair). Cada paso reduce la dispersión, así que el proceso
+// termina. Respeta limit (o el Limit propio de cada certificado) y no toca los
+// IsOverflow, que tampoco cuentan para la media. certs no se modifica. Si la
+// cota no se alcanza devuelve el mejor resultado logrado y un error.
+func BalanceWithinVariance(certs []Certificate, limit, maxVariancePct float64) ([]Certificate, error) {
+	work := make([]Certificate, len(certs))
+	var balanced []int
+	var total float64
+	for i, cert := range certs {
+		work[i] = cert
+		work[i].Orders = append([]Order(nil), cert.Orders...)
+		if !cert.IsOverflow {
+			balanced = append(balanced, i)
+			total += cert.Amount
+		}
+	}
+	if len(balanced) == 0 {
+		return work, nil
+	}
+	mean := total / float64(len(balanced))
+	maxDeviation := mean * maxVariancePct / 100
+
+	for {
+		worst := balanced[0]
+		for _, i := range balanced {
+			if math.Abs(work[i].Amount-mean) > math.Abs(work[worst].Amount-mean) {
+				worst = i
+			}
+		}
+		deviation := math.Abs(work[worst].Amount - mean)
+		if deviation <= maxDeviation+amountTolerance {
+			return work, nil
+		}
+
+		// Candidatos del lado opuesto, empezando por el más alejado
+		above := work[worst].Amount > mean
+		var partners []int
+		for _, i := range balanced {
+			if above && work[i].Amount < work[worst].Amount || !above && work[i].Amount > work[worst].Amount {
+				partners = append(partners, i)
+			}
+		}
+		sort.SliceStable(partners, func(a, b int) bool {
+			if above {
+				return work[partners[a]].Amount < work[partners[b]].Amount
+			}
+			return work[partners[a]].Amount > work[partners[b]].Amount
+		})
+
+		moved := false
+		for _, partner := range partners {
+			high, low := &work[worst], &work[partner]
+			if !above {
+				high, low = low, high
+			}
+			if rebalancePair(high, low, limit) {
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			return work, fmt.Errorf("no se pudo equilibrar: el certificado %d se aparta %.2f%% de la media (máximo %.2f%%)",
+				work[worst].ID, deviation/mean*100, maxVariancePct)
+		}
+	}
+}
+
+// rebalancePair mueve una orden de high a low, o intercambia una de cada uno,
+// eligiendo la transferencia de monto más cercano a la mitad de la diferencia
+// entre ambos. Solo considera transferencias que reducen esa diferencia y dejan
+// a low dentro de su límite. Devuelve false si no hay ninguna.
+func rebalancePair(high, low *Certificate, limit float64) bool {
+	gap := high.Amount - low.Amount
+	lowLimit := limit
+	if low.Limit > 0 {
+		lowLimit = low.Limit
+	}
+	room := lowLimit + defaultEpsilon - low.Amount
+
+	bestFrom, bestTo := -1, -1 // bestTo < 0 indica mover sin intercambiar
+	bestScore := math.Inf(1)
+	consider := func(transfer float64, from, to int) {
+		if transfer <= defaultEpsilon || transfer >= gap-defaultEpsilon || transfer > room {
+			return
+		}
+		if score := math.Abs(transfer - gap/2); score < bestScore {
+			bestScore, bestFrom, bestTo = score, from, to
+		}
+	}
+	for i, x := range high.Orders {
+		consider(x.Amount, i, -1)
+		for j, y := range low.Orders {
+			consider(x.Amount-y.Amount, i, j)
+		}
+	}
+	if bestFrom < 0 {
+		return false
+	}
+
+	moving := high.Orders[bestFrom]
+	high.Orders = append(high.Orders[:bestFrom], high.Orders[bestFrom+1:]...)
+	if bestTo >= 0 {
+		high.Orders = append(high.Orders, low.Orders[bestTo])
+		low.Orders = append(low.Orders[:bestTo], low.Orders[bestTo+1:]...)
+	}
+	low.Orders = append(low.Orders, moving)
+
+	// Recalcular desde las órdenes para no acumular error de redondeo
+	for _, cert := range []*Certificate{high, low} {
+		cert.Amount = 0
+		for _, order := range cert.Orders {
+			cert.Amount += order.Amount
+		}
+	}
+	return true
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestConsolidateUnderfilledMergesLowFillCertificates(t *testing.T) {
+	const limit = 1000
+	full := []Certificate{
+		{ID: 1, Amount: 950, Orders: []Order{{ID: 1, Amount: 600, MerchantID: 1}, {ID: 2, Amount: 350, MerchantID: 2}}},
+		{ID: 2, Amount: 900, Orders: []Order{{ID: 3, Amount: 900, MerchantID: 3}}},
+	}
+	// Tres certificados al 30%: sus órdenes entran en un solo certificado
+	underfilled := []Certificate{
+		{ID: 3, Amount: 300, Orders: []Order{{ID: 4, Amount: 200, MerchantID: 1}, {ID: 5, Amount: 100, MerchantID: 4}}},
+		{ID: 4, Amount: 300, Orders: []Order{{ID: 6, Amount: 300, MerchantID: 2}}},
+		{ID: 5, Amount: 300, Orders: []Order{{ID: 7, Amount: 150, MerchantID: 5}, {ID: 8, Amount: 150, MerchantID: 5}}},
+	}
+	certs := append(append([]Certificate(nil), full...), underfilled...)
+
+	got := ConsolidateUnderfilled(certs, limit, 0.5)
+
+	if len(got) != len(full)+1 {
+		t.Fatalf("got %d certificates, want %d", len(got), len(full)+1)
+	}
+	if !reflect.DeepEqual(got[:len(full)], full) {
+		t.Errorf("well-filled certificates changed: got %+v, want %+v", got[:len(full)], full)
+	}
+	merged := got[len(full)]
+	if merged.ID != 6 || len(merged.Orders) != 5 || merged.Amount != 900 {
+		t.Errorf("consolidated certificate = ID %d, %d orders, %.2f; want ID 6, 5 orders, 900",
+			merged.ID, len(merged.Orders), merged.Amount)
+	}
+}
+
+func TestConsolidateUnderfilledKeepsSetWhenNothingToGain(t *testing.T) {
+	// Dos certificados al 40% y al 70% que no caben juntos con un límite de 700
+	certs := []Certificate{
+		{ID: 1, Amount: 280, Orders: []Order{{ID: 1, Amount: 280}}},
+		{ID: 2, Amount: 490, Orders: []Order{{ID: 2, Amount: 490}}},
+	}
+	if got := ConsolidateUnderfilled(certs, 700, 0.75); !reflect.DeepEqual(got, certs) {
+		t.Errorf("ConsolidateUnderfilled = %+v, want the original set", got)
+	}
+}
+
+func TestConsolidateUnderfilledDefaultLimit(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 300, Orders: []Order{{ID: 1, Amount: 300, MerchantID: 1}}},
+		{ID: 2, Amount: 200, Orders: []Order{{ID: 2, Amount: 200, MerchantID: 2}}},
+	}
+	// Con limit 0 vale el límite por defecto: ambos están casi vacíos y se unen
+	got := ConsolidateUnderfilled(certs, 0, 0.5)
+	if len(got) != 1 || got[0].Amount != 500 || len(got[0].Orders) != 2 {
+		t.Errorf("got %+v, want both orders in a single certificate of 500", got)
+	}
+}
+
+func TestCanMergeAndMergeMergeablePair(t *testing.T) {
+	a := Certificate{ID: 4, Amount: 600, Orders: []Order{{ID: 1, Amount: 600, MerchantID: 1}}}
+	b := Certificate{ID: 9, Amount: 400, Orders: []Order{{ID: 2, Amount: 250, MerchantID: 2}, {ID: 3, Amount: 150, MerchantID: 1}}}
+
+	// Justo en el límite todavía se pueden unir
+	if !CanMerge(a, b, 1000) {
+		t.Fatal("CanMerge = false for a pair that sums exactly to the limit")
+	}
+	merged := Merge(a, b)
+	want := Certificate{ID: 4, Amount: 1000, Orders: []Order{a.Orders[0], b.Orders[0], b.Orders[1]}}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("Merge = %+v, want %+v", merged, want)
+	}
+	if len(a.Orders) != 1 || len(b.Orders) != 2 {
+		t.Error("Merge modified its arguments")
+	}
+}
+
+func TestCanMergeRejectsOverflowingPair(t *testing.T) {
+	a := Certificate{ID: 1, Amount: 600.01, Orders: []Order{{ID: 1, Amount: 600.01}}}
+	b := Certificate{ID: 2, Amount: 400, Orders: []Order{{ID: 2, Amount: 400}}}
+	if CanMerge(a, b, 1000) {
+		t.Error("CanMerge = true for a pair one cent over the limit")
+	}
+}
+
+func TestGreedyMinimizeEmptiesSmallCertificates(t *testing.T) {
+	const limit = 1000
+	// Una orden por certificado: 600+400 y 700+300 entran en dos
+	certs := []Certificate{
+		{ID: 1, Amount: 600, Orders: []Order{{ID: 1, Amount: 600, MerchantID: 1}}},
+		{ID: 2, Amount: 400, Orders: []Order{{ID: 2, Amount: 400, MerchantID: 2}}},
+		{ID: 3, Amount: 700, Orders: []Order{{ID: 3, Amount: 700, MerchantID: 1}}},
+		{ID: 4, Amount: 300, Orders: []Order{{ID: 4, Amount: 300, MerchantID: 3}}},
+	}
+	var orders []Order
+	for _, cert := range certs {
+		orders = append(orders, cert.Orders...)
+	}
+
+	got := GreedyMinimize(certs, limit)
+
+	if len(got) > len(certs)-1 {
+		t.Fatalf("got %d certificates, want at least one fewer than %d", len(got), len(certs))
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d certificates, want the optimal 2", len(got))
+	}
+	if err := VerifyCertificates(orders, got, limit); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestGreedyMinimizeSkipsOverflowCertificates(t *testing.T) {
+	const limit = 1000
+	certs := []Certificate{
+		{ID: 1, Amount: 1500, IsOverflow: true, Orders: []Order{{ID: 1, Amount: 1500, MerchantID: 1}}},
+		{ID: 2, Amount: 100, Orders: []Order{{ID: 2, Amount: 100, MerchantID: 2}}},
+	}
+	// El de desborde no recibe órdenes ni se vacía, y el otro no tiene adónde ir
+	if got := GreedyMinimize(certs, limit); !reflect.DeepEqual(got, certs) {
+		t.Errorf("GreedyMinimize = %+v, want the input unchanged", got)
+	}
+}
+
+func TestSimulatePlacementFirstWithRoomOrNew(t *testing.T) {
+	const limit = 1000
+	certs := []Certificate{
+		{ID: 4, Amount: 950, Orders: []Order{{ID: 1, Amount: 950, MerchantID: 1}}},
+		{ID: 2, Amount: 700, Orders: []Order{{ID: 2, Amount: 700, MerchantID: 2}}},
+		{ID: 9, Amount: 200, Orders: []Order{{ID: 3, Amount: 200, MerchantID: 3}}},
+	}
+	before := cloneCertificates(certs)
+
+	// 100 no entra en el 4 pero sí en el 2, aunque el 9 tenga más espacio
+	if id, newCert := SimulatePlacement(certs, Order{ID: 10, Amount: 100, MerchantID: 1}, limit); id != 2 || newCert {
+		t.Errorf("small order: got (%d, %v), want (2, false)", id, newCert)
+	}
+	// Ninguno tiene lugar para 900: se abriría el certificado 10
+	if id, newCert := SimulatePlacement(certs, Order{ID: 11, Amount: 900, MerchantID: 1}, limit); id != 10 || !newCert {
+		t.Errorf("huge order: got (%d, %v), want (10, true)", id, newCert)
+	}
+	if !reflect.DeepEqual(certs, before) {
+		t.Error("SimulatePlacement modified its input")
+	}
+}
+
+func TestBalanceWithinVarianceSpreadWithinBound(t *testing.T) {
+	const limit, pct = 1000, 10
+	// Lo que deja FFD: dos certificados llenos y uno casi vacío
+	orders := []Order{
+		{ID: 1, Amount: 500, MerchantID: 1}, {ID: 2, Amount: 300, MerchantID: 2}, {ID: 3, Amount: 200, MerchantID: 3},
+		{ID: 4, Amount: 450, MerchantID: 1}, {ID: 5, Amount: 350, MerchantID: 2}, {ID: 6, Amount: 150, MerchantID: 3},
+		{ID: 7, Amount: 100, MerchantID: 4}, {ID: 8, Amount: 100, MerchantID: 5}, {ID: 9, Amount: 50, MerchantID: 6},
+	}
+	certs := []Certificate{
+		{ID: 1, Amount: 1000, Orders: orders[0:3]},
+		{ID: 2, Amount: 950, Orders: orders[3:6]},
+		{ID: 3, Amount: 250, Orders: orders[6:9]},
+	}
+
+	got, err := BalanceWithinVariance(certs, limit, pct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mean := 2200.0 / 3
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, cert := range got {
+		lo, hi = math.Min(lo, cert.Amount), math.Max(hi, cert.Amount)
+	}
+	// Cada uno a lo sumo a pct% de la media, así que entre extremos 2·pct%
+	if hi-mean > mean*pct/100+amountTolerance || mean-lo > mean*pct/100+amountTolerance {
+		t.Errorf("amounts span [%.2f, %.2f], want within %d%% of the mean %.2f", lo, hi, pct, mean)
+	}
+	if hi-lo > 2*mean*pct/100+amountTolerance {
+		t.Errorf("max-min spread %.2f exceeds the bound %.2f", hi-lo, 2*mean*pct/100)
+	}
+	if err := VerifyCertificates(orders, got, limit); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestBalanceWithinVarianceReportsInfeasible(t *testing.T) {
+	// Una sola orden por certificado: no hay nada que mover sin empeorar
+	certs := []Certificate{
+		{ID: 1, Amount: 900, Orders: []Order{{ID: 1, Amount: 900, MerchantID: 1}}},
+		{ID: 2, Amount: 100, Orders: []Order{{ID: 2, Amount: 100, MerchantID: 2}}},
+	}
+	got, err := BalanceWithinVariance(certs, 1000, 5)
+	if err == nil {
+		t.Fatal("BalanceWithinVariance = nil error, want the bound reported as unreachable")
+	}
+	if !reflect.DeepEqual(got, certs) {
+		t.Errorf("got %+v, want the best effort to equal the input", got)
+	}
+}
+
+func TestDissolveCertificateAllOrdersFitElsewhere(t *testing.T) {
+	const limit = 1000
+	orders := []Order{
+		{ID: 1, Amount: 800, MerchantID: 1},
+		{ID: 2, Amount: 600, MerchantID: 2},
+		{ID: 3, Amount: 150, MerchantID: 3},
+		{ID: 4, Amount: 300, MerchantID: 1},
+		{ID: 5, Amount: 1200, MerchantID: 4},
+		{ID: 6, Amount: 50, MerchantID: 2},
+	}
+	certs := []Certificate{
+		{ID: 1, Amount: 800, Orders: []Order{orders[0]}},
+		{ID: 2, Amount: 600, Orders: []Order{orders[1]}},
+		{ID: 3, Amount: 450, Orders: []Order{orders[2], orders[3]}},
+		// Excedido: no recibe órdenes aunque tuviera un límite propio mayor
+		{ID: 4, Amount: 1200, IsOverflow: true, Orders: []Order{orders[4]}},
+		{ID: 5, Amount: 50, Orders: []Order{orders[5]}},
+	}
+	before := cloneCertificates(certs)
+
+	// Las órdenes del 3, de mayor a menor: 300 entra en el 2 y 150 en el 1
+	got, leftover := DissolveCertificate(certs, 3, limit)
+	if len(leftover) != 0 {
+		t.Errorf("leftover = %v, want none", leftover)
+	}
+	want := []Certificate{
+		{ID: 1, Amount: 950, Orders: []Order{orders[0], orders[2]}},
+		{ID: 2, Amount: 900, Orders: []Order{orders[1], orders[3]}},
+		certs[3],
+		certs[4],
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DissolveCertificate = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(certs, before) {
+		t.Error("DissolveCertificate modified its input")
+	}
+
+	// Sin el 5, las órdenes del 2 no tienen dónde ir y vuelven de mayor a menor
+	got, leftover = DissolveCertificate(want[:3], 2, limit)
+	if len(got) != 2 || !reflect.DeepEqual(leftover, []Order{orders[1], orders[3]}) {
+		t.Errorf("got %d certificates and leftover %v, want 2 and orders 2 and 4", len(got), leftover)
+	}
+
+	// Un ID inexistente deja todo igual
+	if got, leftover := DissolveCertificate(certs, 99, limit); !reflect.DeepEqual(got, certs) || leftover != nil {
+		t.Errorf("unknown ID: got %d certificates and leftover %v, want the input unchanged", len(got), leftover)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// printCertificateReport muestra las estadísticas de llenado de los certificados
+// y algunos certificados de ejemplo
+func printCertificateReport(w io.Writer, certificates []Certificate, limit float64, verbose bool) {
+	// Calcular estadísticas de certificados
+	var totalCertificateAmount float64
+	certificateAmounts := make([]float64, len(certificates))
+	for i, cert := range certificates {
+		totalCertificateAmount += cert.Amount
+		certificateAmounts[i] = cert.Amount
+	}
+
+	fmt.Fprintf(w, "  Límite por certificado: $%.2f\n", limit)
+	fmt.Fprintf(w, "  Número real de certificados generados: %d\n", len(certificates))
+	if len(certificates) == 0 {
+		return
+	}
+
+	// Calcular el porcentaje promedio de llenado de los certificados
+	avgFillPercentage := (totalCertificateAmount / float64(len(certificates))) / limit * 100
+	fmt.Fprintf(w, "  Porcentaje promedio de llenado: %.2f%%\n", avgFillPercentage)
+
+	// Ordenar los montos para calcular percentiles
+	sort.Float64s(certificateAmounts)
+	minCertAmount := certificateAmounts[0]
+	maxCertAmount := certificateAmounts[len(certificateAmounts)-1]
+
+	// Calcular percentiles relevantes
+	p25 := percentile(certificateAmounts, 25)
+	p50 := percentile(certificateAmounts, 50) // mediana
+	p75 := percentile(certificateAmounts, 75)
+	p90 := percentile(certificateAmounts, 90)
+
+	fmt.Fprintln(w, "\nDistribución de montos en certificados:")
+	fmt.Fprintf(w, "  Monto mínimo: $%.2f (%.2f%% del límite)\n", minCertAmount, minCertAmount/limit*100)
+	fmt.Fprintf(w, "  Percentil 25: $%.2f (%.2f%% del límite)\n", p25, p25/limit*100)
+	fmt.Fprintf(w, "  Mediana (P50): $%.2f (%.2f%% del límite)\n", p50, p50/limit*100)
+	fmt.Fprintf(w, "  Percentil 75: $%.2f (%.2f%% del límite)\n", p75, p75/limit*100)
+	fmt.Fprintf(w, "  Percentil 90: $%.2f (%.2f%% del límite)\n", p90, p90/limit*100)
+	fmt.Fprintf(w, "  Monto máximo: $%.2f (%.2f%% del límite)\n", maxCertAmount, maxCertAmount/limit*100)
+	fmt.Fprintf(w, "  Margen mínimo respecto del límite: $%.2f\n", MinMargin(certificates, limit))
+
+	if verbose {
+		countStats := OrderCountStats(certificates)
+		fmt.Fprintln(w, "\nÓrdenes por certificado:")
+		fmt.Fprintf(w, "  Mínimo: %d\n", countStats.Min)
+		fmt.Fprintf(w, "  Promedio: %.2f\n", countStats.Mean)
+		fmt.Fprintf(w, "  Mediana (P50): %.2f\n", countStats.P50)
+		fmt.Fprintf(w, "  Percentil 90: %.2f\n", countStats.P90)
+		fmt.Fprintf(w, "  Máximo: %d\n", countStats.Max)
+		fmt.Fprintf(w, "  Correlación con el llenado: %.3f\n", CorrFillVsOrderCount(certificates, limit))
+		fmt.Fprintf(w, "  Deriva máxima de acumulación de montos: $%g\n", MaxAccumulationDrift(certificates))
+
+		merchantStats := MerchantsPerCertStats(certificates)
+		fmt.Fprintln(w, "\nComerciantes distintos por certificado:")
+		fmt.Fprintf(w, "  Mínimo: %d\n", merchantStats.Min)
+		fmt.Fprintf(w, "  Mediana (P50): %.2f\n", merchantStats.P50)
+		fmt.Fprintf(w, "  Percentil 90: %.2f\n", merchantStats.P90)
+		fmt.Fprintf(w, "  Máximo: %d\n", merchantStats.Max)
+	}
+
+	// Mostrar ejemplo de certificados (primeros y últimos)
+	fmt.Fprintln(w, "\nPrimeros 3 certificados:")
+	for i := 0; i < 3 && i < len(certificates); i++ {
+		printCertificateLine(w, certificates[i], limit)
+	}
+
+	fmt.Fprintln(w, "\nÚltimos 3 certificados (de equilibrio):")
+	for i := max(len(certificates)-3, 0); i < len(certificates); i++ {
+		printCertificateLine(w, certificates[i], limit)
+	}
+}
+
+// printCertificateLine muestra el resumen de un certificado en una línea
+func printCertificateLine(w io.Writer, cert Certificate, limit float64) {
+	fmt.Fprintf(w, "  Certificado ID: %d, Monto: $%.2f (%.2f%%), Órdenes: %d\n",
+		cert.ID, cert.Amount, cert.Amount/limit*100, len(cert.Orders))
+}
+
+// printMarginReport muestra el margen (límite - monto) de cada certificado y el
+// mínimo entre todos, marcando los que exceden su límite
+func printMarginReport(w io.Writer, certs []Certificate, limit float64) {
+	for _, cert := range certs {
+		margin := certificateMargin(cert, limit)
+		note := ""
+		switch {
+		case cert.IsOverflow:
+			note = " (excepción)"
+		case margin < 0:
+			note = " EXCEDE EL LÍMITE"
+		}
+		fmt.Fprintf(w, "  Certificado ID: %d, Margen: $%.2f%s\n", cert.ID, margin, note)
+	}
+	fmt.Fprintf(w, "  Margen mínimo: $%.2f\n", MinMargin(certs, limit))
+}
+
+// PrintCertificateTree muestra cada certificado seguido de sus órdenes con
+// comerciante y monto. Si maxOrders > 0, los certificados con más órdenes solo
+// muestran las primeras y las últimas hasta completar maxOrders, indicando
+// cuántas se omitieron.
+func PrintCertificateTree(w io.Writer, certs []Certificate, limit float64, maxOrders int) {
+	for _, cert := range certs {
+		fmt.Fprintf(w, "Certificado %d: $%.2f (%.2f%% del límite), %d órdenes\n",
+			cert.ID, cert.Amount, cert.Amount/limit*100, len(cert.Orders))
+
+		head, tail := len(cert.Orders), 0
+		if maxOrders > 0 && len(cert.Orders) > maxOrders {
+			head = (maxOrders + 1) / 2
+			tail = maxOrders - head
+		}
+		for _, order := range cert.Orders[:head] {
+			printOrderLine(w, order)
+		}
+		if omitted := len(cert.Orders) - head - tail; omitted > 0 {
+			fmt.Fprintf(w, "    ... %d órdenes omitidas\n", omitted)
+			for _, order := range cert.Orders[len(cert.Orders)-tail:] {
+				printOrderLine(w, order)
+			}
+		}
+	}
+}
+
+// printOrderLine muestra una orden como rama del árbol de PrintCertificateTree
+func printOrderLine(w io.Writer, order Order) {
+	fmt.Fprintf(w, "    Orden %d, comerciante %d: $%.2f\n", order.ID, order.MerchantID, order.Amount)
+}
+
+// RunSummary resume una ejecución en un formato apto para procesar
+// automáticamente, como la salida de la simulación con -format json
+type RunSummary struct {
+	Merchants               int     // Comerciantes distintos
+	Orders                  int     // Órdenes empaquetadas
+	TotalAmount             float64 // Monto total de las órdenes
+	Limit                   float64 // Monto máximo por certificado
+	TheoreticalCertificates float64 // TotalAmount / Limit
+	Certificates            int
+	AvgFillPercent          float64 // Llenado promedio de los certificados, en porcentaje del límite
+
+	// Distribución de los montos de los certificados
+	MinAmount float64
+	P25Amount float64
+	P50Amount float64
+	P75Amount float64
+	P90Amount float64
+	MaxAmount float64
+
+	OrdersPerCertificate OrderCountSummary
+
+	// Duración del empaquetado y órdenes empaquetadas por segundo (ver SetPackingTime)
+	PackingSeconds  float64
+	OrdersPerSecond float64
+}
+
+// SetPackingTime registra cuánto tardó el empaquetado y el rendimiento resultante
+func (s *RunSummary) SetPackingTime(d time.Duration) {
+	s.PackingSeconds = d.Seconds()
+	s.OrdersPerSecond = Throughput(s.Orders, d)
+}
+
+// NewRunSummary calcula el resumen de los certificados generados con limit a
+// partir de orders
+func NewRunSummary(orders []Order, certificates []Certificate, limit float64) RunSummary {
+	summary := RunSummary{
+		Orders:               len(orders),
+		Limit:                limit,
+		Certificates:         len(certificates),
+		OrdersPerCertificate: OrderCountStats(certificates),
+	}
+
+	merchants := make(map[int]bool)
+	for _, order := range orders {
+		merchants[order.MerchantID] = true
+		summary.TotalAmount += order.Amount
+	}
+	summary.Merchants = len(merchants)
+	if limit > 0 {
+		summary.TheoreticalCertificates = summary.TotalAmount / limit
+	}
+	if len(certificates) == 0 {
+		return summary
+	}
+
+	amounts := make([]float64, len(certificates))
+	var certified float64
+	for i, cert := range certificates {
+		amounts[i] = cert.Amount
+		certified += cert.Amount
+	}
+	sort.Float64s(amounts)
+	if limit > 0 {
+		summary.AvgFillPercent = certified / float64(len(certificates)) / limit * 100
+	}
+	summary.MinAmount = amounts[0]
+	summary.P25Amount = percentile(amounts, 25)
+	summary.P50Amount = percentile(amounts, 50)
+	summary.P75Amount = percentile(amounts, 75)
+	summary.P90Amount = percentile(amounts, 90)
+	summary.MaxAmount = amounts[len(amounts)-1]
+	return summary
+}
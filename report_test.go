@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintCertificateTreeHeadersAndOrders(t *testing.T) {
+	big := Certificate{ID: 2, Amount: 500}
+	for i := range 10 {
+		big.Orders = append(big.Orders, Order{ID: 100 + i, Amount: 50, MerchantID: 7})
+	}
+	certs := []Certificate{
+		{ID: 1, Amount: 750.5, Orders: []Order{{ID: 1, Amount: 500.5, MerchantID: 3}, {ID: 2, Amount: 250, MerchantID: 4}}},
+		big,
+	}
+
+	var buf bytes.Buffer
+	PrintCertificateTree(&buf, certs, 1000, 4)
+	out := buf.String()
+
+	for _, want := range []string{
+		"Certificado 1: $750.50 (75.05% del límite), 2 órdenes\n",
+		"    Orden 1, comerciante 3: $500.50\n",
+		"    Orden 2, comerciante 4: $250.00\n",
+		"Certificado 2: $500.00 (50.00% del límite), 10 órdenes\n",
+		// Truncado a 4: las dos primeras, el aviso y las dos últimas
+		"    Orden 101, comerciante 7: $50.00\n    ... 6 órdenes omitidas\n    Orden 108, comerciante 7: $50.00\n",
+		"    Orden 109, comerciante 7: $50.00\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("tree output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Orden 102,") {
+		t.Errorf("truncated certificate still shows order 102:\n%s", out)
+	}
+}
+
+func TestRunSummarySetPackingTime(t *testing.T) {
+	orders := make([]Order, 1200)
+	for i := range orders {
+		orders[i] = Order{ID: i + 1, Amount: 10, MerchantID: i%3 + 1}
+	}
+	certs := []Certificate{{ID: 1, Amount: 12000, Orders: orders}}
+
+	summary := NewRunSummary(orders, certs, 20000)
+	summary.SetPackingTime(1500 * time.Millisecond)
+	if summary.PackingSeconds != 1.5 || summary.OrdersPerSecond != 800 {
+		t.Errorf("PackingSeconds %v, OrdersPerSecond %v; want 1.5 and 800", summary.PackingSeconds, summary.OrdersPerSecond)
+	}
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// FillCV calcula el coeficiente de variación (desviación estándar / media) de los
+// porcentajes de llenado de los certificados. Un valor menor indica llenados más
+// uniformes; 0 significa que todos los certificados tienen el mismo monto.
+func FillCV(certs []Certificate, limit float64) float64 {
+	if len(certs) == 0 || limit <= 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, cert := range certs {
+		sum += cert.Amount / limit
+	}
+	mean := sum / float64(len(certs))
+	if mean == 0 {
+		return 0
+	}
+
+	// Varianza poblacional de los llenados
+	var variance float64
+	for _, cert := range certs {
+		diff := cert.Amount/limit - mean
+		variance += diff * diff
+	}
+	variance /= float64(len(certs))
+
+	return math.Sqrt(variance) / mean
+}
+
+// OrderCountSummary resume cuántas órdenes contiene cada certificado
+type OrderCountSummary struct {
+	Min  int
+	Max  int
+	Mean float64
+	P50  float64
+	P90  float64
+}
+
+// OrderCountStats calcula la distribución de la cantidad de órdenes por certificado
+func OrderCountStats(certs []Certificate) OrderCountSummary {
+	if len(certs) == 0 {
+		return OrderCountSummary{}
+	}
+
+	counts := make([]float64, len(certs))
+	summary := OrderCountSummary{Min: len(certs[0].Orders), Max: len(certs[0].Orders)}
+	total := 0
+	for i, cert := range certs {
+		n := len(cert.Orders)
+		counts[i] = float64(n)
+		total += n
+		if n < summary.Min {
+			summary.Min = n
+		}
+		if n > summary.Max {
+			summary.Max = n
+		}
+	}
+	sort.Float64s(counts)
+
+	summary.Mean = float64(total) / float64(len(certs))
+	summary.P50 = percentile(counts, 50)
+	summary.P90 = percentile(counts, 90)
+	return summary
+}
+
+// MerchantsPerCertSummary resume cuántos comerciantes distintos hay en cada
+// certificado
+type MerchantsPerCertSummary struct {
+	Min int
+	P50 float64
+	P90 float64
+	Max int
+}
+
+// MerchantsPerCertStats calcula la distribución de la cantidad de comerciantes
+// distintos por certificado, útil para ajustar MaxMerchantsPerCert y
+// MerchantCohesion
+func MerchantsPerCertStats(certs []Certificate) MerchantsPerCertSummary {
+	if len(certs) == 0 {
+		return MerchantsPerCertSummary{}
+	}
+
+	counts := make([]float64, len(certs))
+	for i, cert := range certs {
+		merchants := make(map[int]bool)
+		for _, order := range cert.Orders {
+			merchants[order.MerchantID] = true
+		}
+		counts[i] = float64(len(merchants))
+	}
+	sort.Float64s(counts)
+
+	return MerchantsPerCertSummary{
+		Min: int(counts[0]),
+		P50: percentile(counts, 50),
+		P90: percentile(counts, 90),
+		Max: int(counts[len(counts)-1]),
+	}
+}
+
+// CorrFillVsOrderCount calcula la correlación de Pearson entre la cantidad de
+// órdenes de cada certificado y su llenado (Amount/limit), entre -1 y 1. Un
+// valor positivo indica que los certificados con más órdenes tienden a quedar
+// más llenos. Devuelve 0 con menos de dos certificados o si alguna de las dos
+// variables es constante.
+func CorrFillVsOrderCount(certs []Certificate, limit float64) float64 {
+	if len(certs) < 2 || limit <= 0 {
+		return 0
+	}
+
+	n := float64(len(certs))
+	var meanCount, meanFill float64
+	for _, cert := range certs {
+		meanCount += float64(len(cert.Orders))
+		meanFill += cert.Amount / limit
+	}
+	meanCount /= n
+	meanFill /= n
+
+	var cov, varCount, varFill float64
+	for _, cert := range certs {
+		dc := float64(len(cert.Orders)) - meanCount
+		df := cert.Amount/limit - meanFill
+		cov += dc * df
+		varCount += dc * dc
+		varFill += df * df
+	}
+	if varCount == 0 || varFill == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varCount*varFill)
+}
+
+// IssuanceCost estima el costo de emitir los certificados cuando cada uno tiene
+// una tarifa fija más una tarifa por cada orden que contiene
+func IssuanceCost(certs []Certificate, flatFee, perOrderFee float64) float64 {
+	var cost float64
+	for _, cert := range certs {
+		cost += flatFee + perOrderFee*float64(len(cert.Orders))
+	}
+	return cost
+}
+
+// TagTotals suma los montos de las órdenes certificadas agrupados por el valor
+// de la etiqueta key. Las órdenes sin esa etiqueta se acumulan bajo "".
+func TagTotals(certs []Certificate, key string) map[string]float64 {
+	totals := make(map[string]float64)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			totals[order.Tags[key]] += order.Amount
+		}
+	}
+	return totals
+}
+
+// RemainingCapacities devuelve el espacio libre (limit - Amount) de cada
+// certificado, ordenado por ID. Los certificados que exceden el límite
+// informan 0 en lugar de un valor negativo.
+func RemainingCapacities(certs []Certificate, limit float64) []float64 {
+	sorted := append([]Certificate(nil), certs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	capacities := make([]float64, len(sorted))
+	for i, cert := range sorted {
+		capacities[i] = math.Max(limit-cert.Amount, 0)
+	}
+	return capacities
+}
+
+// MaxCertificateAmount devuelve el monto del certificado más cargado
+func MaxCertificateAmount(certs []Certificate) float64 {
+	var maxAmount float64
+	for i, cert := range certs {
+		if i == 0 || cert.Amount > maxAmount {
+			maxAmount = cert.Amount
+		}
+	}
+	return maxAmount
+}
+
+// FillBucketCounts cuenta los certificados por tramo de llenado (Amount/limit)
+// de bucketPct puntos porcentuales, como datos en lugar de un listado: la clave
+// es el límite inferior del tramo en porcentaje, truncado a entero, así que con
+// tramos de 10 un certificado lleno a la mitad cuenta en 50 y uno lleno al 95%
+// en 90. Un certificado exactamente lleno cuenta en el tramo que termina en
+// 100% y los que exceden el límite en tramos de 100 en adelante. Con limit o
+// bucketPct no positivos devuelve nil.
+func FillBucketCounts(certs []Certificate, limit float64, bucketPct float64) map[int]int {
+	if limit <= 0 || bucketPct <= 0 {
+		return nil
+	}
+	counts := make(map[int]int)
+	for _, cert := range certs {
+		// El margen absorbe el redondeo de casos como 0.3*100 = 29.999...
+		bucket := math.Floor(cert.Amount/limit*100/bucketPct+1e-9) * bucketPct
+		if bucket >= 100 && cert.Amount <= limit+defaultEpsilon {
+			// Lleno dentro de la tolerancia: el último tramo por debajo de 100%
+			bucket = math.Ceil(100/bucketPct-1) * bucketPct
+		}
+		counts[int(bucket)]++
+	}
+	return counts
+}
+
+// MaxAccumulationDrift compara el Amount de cada certificado, acumulado con
+// sumas sucesivas durante el empaquetado, con la suma compensada (Kahan) de sus
+// órdenes y devuelve la mayor diferencia absoluta. Sirve como autocontrol del
+// error de redondeo en certificados con miles de órdenes.
+func MaxAccumulationDrift(certs []Certificate) float64 {
+	var maxDrift float64
+	for _, cert := range certs {
+		var sum kahanSum
+		for _, order := range cert.Orders {
+			sum.add(order.Amount)
+		}
+		maxDrift = math.Max(maxDrift, math.Abs(cert.Amount-sum.value()))
+	}
+	return maxDrift
+}
+
+// kahanSum acumula montos con la suma compensada de Kahan, que arrastra el
+// error de redondeo de cada suma para corregir la siguiente
+type kahanSum struct {
+	sum          float64
+	compensation float64
+}
+
+func (k *kahanSum) add(x float64) {
+	y := x - k.compensation
+	t := k.sum + y
+	k.compensation = (t - k.sum) - y
+	k.sum = t
+}
+
+func (k *kahanSum) value() float64 {
+	return k.sum
+}
+
+// Throughput devuelve cuántas órdenes por segundo representa procesar orders
+// órdenes en d, o 0 si d no es positiva
+func Throughput(orders int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(orders) / d.Seconds()
+}
+
+// MinMargin devuelve el menor margen (límite - Amount) entre los certificados,
+// usando el Limit propio de cada uno si lo tiene. Un valor negativo indica que
+// algún certificado excede su límite. Los certificados IsOverflow se omiten,
+// ya que se emiten a propósito por encima del límite. Sin certificados
+// devuelve 0.
+func MinMargin(certs []Certificate, limit float64) float64 {
+	var minMargin float64
+	first := true
+	for _, cert := range certs {
+		if cert.IsOverflow {
+			continue
+		}
+		margin := certificateMargin(cert, limit)
+		if first || margin < minMargin {
+			minMargin = margin
+			first = false
+		}
+	}
+	return minMargin
+}
+
+// certificateMargin devuelve el espacio que le queda al certificado respecto de
+// su Limit propio o, si no tiene, de limit
+func certificateMargin(cert Certificate, limit float64) float64 {
+	if cert.Limit > 0 {
+		limit = cert.Limit
+	}
+	return limit - cert.Amount
+}
+
+// EfficiencyScore resume la calidad de un empaquetado en un valor entre 0 y 1,
+// promediando dos razones:
+//
+//	llenado  = suma de Amount de los certificados / (len(certs) * limit)
+//	cantidad = ceil(suma de montos de orders / limit) / len(certs)
+//
+// La primera mide el espacio aprovechado y la segunda qué tan cerca está la
+// cantidad de certificados de la cota inferior trivial. Cada razón se acota a
+// [0, 1]; sin certificados o con limit <= 0 el resultado es 0.
+func EfficiencyScore(certs []Certificate, orders []Order, limit float64) float64 {
+	if len(certs) == 0 || limit <= 0 {
+		return 0
+	}
+
+	var certified float64
+	for _, cert := range certs {
+		certified += cert.Amount
+	}
+	fill := certified / (float64(len(certs)) * limit)
+
+	var total float64
+	for _, order := range orders {
+		total += order.Amount
+	}
+	count := math.Ceil(total/limit) / float64(len(certs))
+
+	clamp := func(v float64) float64 { return math.Min(math.Max(v, 0), 1) }
+	return (clamp(fill) + clamp(count)) / 2
+}
+
+// TopCertificatesByAmount devuelve los k certificados de mayor monto, de mayor a
+// menor y desempatando por ID. Si k supera la cantidad de certificados se
+// devuelven todos. El resultado es una copia; certs no se modifica.
+func TopCertificatesByAmount(certs []Certificate, k int) []Certificate {
+	if k <= 0 {
+		return nil
+	}
+	sorted := append([]Certificate(nil), certs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Amount != sorted[j].Amount {
+			return sorted[i].Amount > sorted[j].Amount
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	if k > len(sorted) {
+		k = len(sorted)
+	}
+	return sorted[:k]
+}
+
+// SoftLimitPenalty calcula la penalización de un empaquetado con límite blando:
+// por cada certificado que supera softLimit, el exceso multiplicado por perDollar
+func SoftLimitPenalty(certs []Certificate, softLimit, perDollar float64) float64 {
+	var penalty float64
+	for _, cert := range certs {
+		if over := cert.Amount - softLimit; over > 0 {
+			penalty += over * perDollar
+		}
+	}
+	return penalty
+}
+
+// AmountTier resume las órdenes con monto en [Lower, Upper)
+type AmountTier struct {
+	Lower float64 // -Inf en el primer tramo
+	Upper float64 // +Inf en el último tramo
+	Count int
+	Total float64
+}
+
+// TierReport agrupa las órdenes en tramos de monto delimitados por boundaries
+// y devuelve la cantidad y el total de cada tramo. Con n límites hay n+1
+// tramos; cada límite pertenece al tramo que comienza en él, así que con
+// boundaries {100, 500} una orden de 100 cae en [100, 500).
+func TierReport(orders []Order, boundaries []float64) []AmountTier {
+	bounds := append([]float64(nil), boundaries...)
+	sort.Float64s(bounds)
+
+	tiers := make([]AmountTier, len(bounds)+1)
+	for i := range tiers {
+		tiers[i].Lower = math.Inf(-1)
+		tiers[i].Upper = math.Inf(1)
+		if i > 0 {
+			tiers[i].Lower = bounds[i-1]
+		}
+		if i < len(bounds) {
+			tiers[i].Upper = bounds[i]
+		}
+	}
+
+	for _, order := range orders {
+		i := sort.Search(len(bounds), func(i int) bool {
+			return bounds[i] > order.Amount
+		})
+		tiers[i].Count++
+		tiers[i].Total += order.Amount
+	}
+	return tiers
+}
+
+// GiniCoefficient mide la desigualdad de los montos entre certificados: 0 si
+// todos tienen el mismo monto y (n-1)/n, cerca de 1, si uno solo concentra todo.
+// Sin certificados o con monto total 0 devuelve 0.
+func GiniCoefficient(certs []Certificate) float64 {
+	amounts := make([]float64, len(certs))
+	var total float64
+	for i, cert := range certs {
+		amounts[i] = cert.Amount
+		total += cert.Amount
+	}
+	if len(amounts) == 0 || total == 0 {
+		return 0
+	}
+	sort.Float64s(amounts)
+
+	// G = 2·Σ(i·x_i) / (n·Σx) - (n+1)/n, con i desde 1 sobre los montos ordenados
+	var weighted float64
+	for i, amount := range amounts {
+		weighted += float64(i+1) * amount
+	}
+	n := float64(len(amounts))
+	return 2*weighted/(n*total) - (n+1)/n
+}
+
+// MinCertificatesLowerBound devuelve una cota inferior de la cantidad de
+// certificados necesaria para las órdenes con el límite dado: el mayor entre
+// ceil(monto total / limit) y la cantidad de órdenes que superan limit/2, ya que
+// dos de ellas nunca comparten certificado. El total se compara con la misma
+// tolerancia que el empaquetado, para que una suma justa en el límite con ruido
+// de punto flotante no cuente un certificado de más.
+func MinCertificatesLowerBound(orders []Order, limit float64) int {
+	if limit <= 0 {
+		return 0
+	}
+	var total float64
+	large := 0
+	for _, order := range orders {
+		total += order.Amount
+		if order.Amount > limit/2 {
+			large++
+		}
+	}
+	return max(int(math.Ceil((total-defaultEpsilon)/limit)), large, 0)
+}
+
+// PackingRatio divide la cantidad de certificados por MinCertificatesLowerBound.
+// Nunca es menor que 1 para un empaquetado válido; cuanto más cerca de 1, más
+// cerca del óptimo. Si la cota es 0 devuelve 0.
+func PackingRatio(certs []Certificate, orders []Order, limit float64) float64 {
+	bound := MinCertificatesLowerBound(orders, limit)
+	if bound == 0 {
+		return 0
+	}
+	return float64(len(certs)) / float64(bound)
+}
+
+// SensitivityToLimit empaqueta las órdenes con cada límite candidato y devuelve
+// cuántos certificados resultan con cada uno, para elegir el límite en la
+// planificación. Los límites con los que alguna orden excede el límite por sí
+// misma no aparecen en el resultado: su cuenta no cubriría todas las órdenes y
+// no sería comparable con la del resto. Un límite <= 0 usa el límite por
+// defecto, como PackOptions.
+func SensitivityToLimit(orders []Order, limits []float64) map[float64]int {
+	counts := make(map[float64]int, len(limits))
+	seen := make(map[float64]bool, len(limits))
+	for _, limit := range limits {
+		if seen[limit] {
+			continue
+		}
+		seen[limit] = true
+		result := GenerateCertificates(orders, PackOptions{Limit: limit})
+		if len(result.Unplaceable) > 0 {
+			continue
+		}
+		counts[limit] = len(result.Certificates)
+	}
+	return counts
+}
+
+// MinLimitForCertCount busca, con búsqueda binaria al centavo, el menor límite
+// con el que el empaquetado produce como mucho k certificados: la inversa de
+// SensitivityToLimit, para fijar el límite según una cantidad de lotes. Parte
+// de la cota max(orden más grande, total/k), por debajo de la cual ningún
+// empaquetado alcanza, y un límite con el que alguna orden no se coloca nunca
+// cumple. Como la heurística no es estrictamente monótona, se
+// garantiza que el límite devuelto cumple y que un centavo menos no. Devuelve 0
+// sin órdenes, con k < 1 o si ni el monto total como límite lo logra.
+func MinLimitForCertCount(orders []Order, k int) float64 {
+	if len(orders) == 0 || k < 1 {
+		return 0
+	}
+	var total, largest float64
+	for _, order := range orders {
+		total += order.Amount
+		largest = math.Max(largest, order.Amount)
+	}
+
+	// Un límite que deja órdenes afuera no cumple aunque arme pocos certificados
+	fits := func(cents int64) bool {
+		limit := float64(cents) / 100
+		result := GenerateCertificates(orders, PackOptions{Limit: limit})
+		if len(result.Unplaceable) > 0 || len(result.Rejected) > 0 {
+			return false
+		}
+		return len(result.Certificates) <= k
+	}
+	lo := int64(math.Ceil(math.Max(largest, total/float64(k))*100 - amountTolerance))
+	hi := int64(math.Ceil(total*100 - amountTolerance))
+	if hi < lo {
+		hi = lo
+	}
+	if !fits(hi) {
+		return 0
+	}
+	// hi siempre cumple y lo-1 no: o se probó o está por debajo de la cota
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if fits(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return float64(hi) / 100
+}
+
+// ApproxPercentile estima un percentil de una secuencia de montos a medida que
+// llegan, con el algoritmo P² de Jain y Chlamtac: guarda solo cinco marcadores,
+// así que la memoria es constante y cada Add cuesta O(1), en lugar de guardar y
+// ordenar todos los valores como percentile. A cambio el resultado es
+// aproximado: con distribuciones suaves y miles de valores el error suele ser
+// menor al 1% del rango intercuartil, pero puede ser mayor con pocas
+// observaciones, distribuciones con saltos o datos ya ordenados. Con menos de
+// cinco valores el resultado es exacto.
+type ApproxPercentile struct {
+	p       float64    // Cuantil buscado, en [0, 1]
+	count   int        // Valores observados
+	heights [5]float64 // Alturas de los marcadores (estimaciones de los cuantiles 0, p/2, p, (1+p)/2 y 1)
+	pos     [5]float64 // Posiciones reales de los marcadores, desde 1
+	desired [5]float64 // Posiciones deseadas de los marcadores
+	incr    [5]float64 // Avance de las posiciones deseadas por cada valor
+}
+
+// NewApproxPercentile crea un estimador del percentil p, entre 0 y 100 como en
+// percentile
+func NewApproxPercentile(p float64) *ApproxPercentile {
+	q := math.Min(math.Max(p/100, 0), 1)
+	return &ApproxPercentile{
+		p:       q,
+		pos:     [5]float64{1, 2, 3, 4, 5},
+		desired: [5]float64{1, 1 + 2*q, 1 + 4*q, 3 + 2*q, 5},
+		incr:    [5]float64{0, q / 2, q, (1 + q) / 2, 1},
+	}
+}
+
+// Add incorpora un valor a la estimación
+func (a *ApproxPercentile) Add(x float64) {
+	if a.count < len(a.heights) {
+		a.heights[a.count] = x
+		a.count++
+		if a.count == len(a.heights) {
+			sort.Float64s(a.heights[:])
+		}
+		return
+	}
+	a.count++
+
+	// Celda del nuevo valor, ampliando los extremos si hace falta
+	var k int
+	switch {
+	case x < a.heights[0]:
+		a.heights[0] = x
+		k = 0
+	case x >= a.heights[4]:
+		a.heights[4] = x
+		k = 3
+	default:
+		// heights[0] <= x < heights[4], así que k termina entre 0 y 3
+		for x >= a.heights[k+1] {
+			k++
+		}
+	}
+	for i := k + 1; i < len(a.pos); i++ {
+		a.pos[i]++
+	}
+	for i := range a.desired {
+		a.desired[i] += a.incr[i]
+	}
+
+	// Acercar los marcadores intermedios a su posición deseada
+	for i := 1; i <= 3; i++ {
+		d := a.desired[i] - a.pos[i]
+		if !(d >= 1 && a.pos[i+1]-a.pos[i] > 1) && !(d <= -1 && a.pos[i-1]-a.pos[i] < -1) {
+			continue
+		}
+		s := math.Copysign(1, d)
+		h := a.parabolic(i, s)
+		if !(a.heights[i-1] < h && h < a.heights[i+1]) {
+			h = a.linear(i, s)
+		}
+		a.heights[i] = h
+		a.pos[i] += s
+	}
+}
+
+// parabolic ajusta la altura del marcador i con la fórmula parabólica de P²
+func (a *ApproxPercentile) parabolic(i int, s float64) float64 {
+	q, n := &a.heights, &a.pos
+	return q[i] + s/(n[i+1]-n[i-1])*((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+		(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// linear ajusta la altura del marcador i interpolando hacia su vecino en la
+// dirección s, cuando la fórmula parabólica rompería el orden de los marcadores
+func (a *ApproxPercentile) linear(i int, s float64) float64 {
+	j := i + int(s)
+	return a.heights[i] + s*(a.heights[j]-a.heights[i])/(a.pos[j]-a.pos[i])
+}
+
+// Value devuelve la estimación actual del percentil, o 0 sin valores
+func (a *ApproxPercentile) Value() float64 {
+	if a.count < len(a.heights) {
+		values := append([]float64(nil), a.heights[:a.count]...)
+		sort.Float64s(values)
+		return percentile(values, a.p*100)
+	}
+	return a.heights[2]
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFillCVLowerForBalancedPacking(t *testing.T) {
+	const limit = 1000
+	// Las mismas órdenes repartidas de dos formas: 600+400 | 500+500 contra 900 | 600 | 500
+	balanced := []Certificate{
+		{ID: 1, Amount: 1000, Orders: []Order{{ID: 1, Amount: 600}, {ID: 2, Amount: 400}}},
+		{ID: 2, Amount: 1000, Orders: []Order{{ID: 3, Amount: 500}, {ID: 4, Amount: 500}}},
+	}
+	skewed := []Certificate{
+		{ID: 1, Amount: 900, Orders: []Order{{ID: 3, Amount: 500}, {ID: 2, Amount: 400}}},
+		{ID: 2, Amount: 600, Orders: []Order{{ID: 1, Amount: 600}}},
+		{ID: 3, Amount: 500, Orders: []Order{{ID: 4, Amount: 500}}},
+	}
+
+	if cv := FillCV(balanced, limit); cv != 0 {
+		t.Errorf("FillCV of equal fills = %v, want 0", cv)
+	}
+	// Llenados 0.9, 0.6 y 0.5: media 2/3, desvío poblacional sqrt(0.26/9)
+	want := math.Sqrt(0.26/9) / (2.0 / 3)
+	if cv := FillCV(skewed, limit); math.Abs(cv-want) > 1e-12 {
+		t.Errorf("FillCV(skewed) = %v, want %v", cv, want)
+	}
+	if FillCV(balanced, limit) >= FillCV(skewed, limit) {
+		t.Error("the balanced packing should have the lower coefficient of variation")
+	}
+}
+
+func TestFillCVDegenerateInputs(t *testing.T) {
+	if cv := FillCV(nil, 1000); cv != 0 {
+		t.Errorf("FillCV(nil) = %v, want 0", cv)
+	}
+	if cv := FillCV([]Certificate{{Amount: 10}}, 0); cv != 0 {
+		t.Errorf("FillCV with limit 0 = %v, want 0", cv)
+	}
+}
+
+func TestOrderCountStatsSmallSet(t *testing.T) {
+	// Certificados con 3, 1, 10, 2 y 4 órdenes
+	var certs []Certificate
+	nextID := 1
+	for i, n := range []int{3, 1, 10, 2, 4} {
+		cert := Certificate{ID: i + 1}
+		for range n {
+			cert.Orders = append(cert.Orders, Order{ID: nextID, Amount: 10, MerchantID: 1})
+			cert.Amount += 10
+			nextID++
+		}
+		certs = append(certs, cert)
+	}
+
+	got := OrderCountStats(certs)
+	// Ordenadas: 1 2 3 4 10; P90 interpola entre 4 y 10 en el índice 3.6
+	want := OrderCountSummary{Min: 1, Max: 10, Mean: 4, P50: 3, P90: 7.6}
+	if got.Min != want.Min || got.Max != want.Max || got.Mean != want.Mean ||
+		got.P50 != want.P50 || math.Abs(got.P90-want.P90) > 1e-9 {
+		t.Errorf("OrderCountStats = %+v, want %+v", got, want)
+	}
+
+	if empty := OrderCountStats(nil); empty != (OrderCountSummary{}) {
+		t.Errorf("OrderCountStats(nil) = %+v, want zero value", empty)
+	}
+}
+
+func TestIssuanceCostKnownFees(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 300, Orders: []Order{{ID: 1, Amount: 100}, {ID: 2, Amount: 200}}},
+		{ID: 2, Amount: 450, Orders: []Order{{ID: 3, Amount: 150}, {ID: 4, Amount: 150}, {ID: 5, Amount: 150}}},
+		{ID: 3, Amount: 90, Orders: []Order{{ID: 6, Amount: 90}}},
+	}
+	// 3 certificados a $25 más 6 órdenes a $1.50
+	if got, want := IssuanceCost(certs, 25, 1.5), 84.0; got != want {
+		t.Errorf("IssuanceCost = %v, want %v", got, want)
+	}
+	if got := IssuanceCost(nil, 25, 1.5); got != 0 {
+		t.Errorf("IssuanceCost(nil) = %v, want 0", got)
+	}
+}
+
+func TestTagTotalsByRegionAcrossPacking(t *testing.T) {
+	// Las etiquetas llegan desde el CSV y tienen que sobrevivir al empaquetado
+	csv := "id,amount,merchant_id,region,channel\n" +
+		"1,400,1,norte,web\n" +
+		"2,250.50,2,sur,pos\n" +
+		"3,300,1,norte,\n" +
+		"4,120,3,,web\n" +
+		"5,99.50,2,sur,web\n"
+	orders, err := LoadOrdersCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: 500})
+	if len(result.Certificates) < 2 {
+		t.Fatalf("got %d certificates, want the orders spread over several", len(result.Certificates))
+	}
+
+	// Y también a la exportación
+	var buf bytes.Buffer
+	if err := WriteCertificatesJSON(&buf, result.Certificates); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := LoadCertificatesJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"norte": 700, "sur": 350, "": 120}
+	if got := TagTotals(exported, "region"); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagTotals(region) = %v, want %v", got, want)
+	}
+	want = map[string]float64{"web": 619.5, "pos": 250.5, "": 300}
+	if got := TagTotals(exported, "channel"); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagTotals(channel) = %v, want %v", got, want)
+	}
+}
+
+func TestRemainingCapacitiesInIDOrder(t *testing.T) {
+	// Desordenados a propósito; el certificado 2 excede el límite
+	certs := []Certificate{
+		{ID: 3, Amount: 250},
+		{ID: 1, Amount: 1000},
+		{ID: 2, Amount: 1000.75},
+		{ID: 4, Amount: 0},
+	}
+	want := []float64{0, 0, 750, 1000}
+	if got := RemainingCapacities(certs, 1000); !reflect.DeepEqual(got, want) {
+		t.Errorf("RemainingCapacities = %v, want %v", got, want)
+	}
+	if certs[0].ID != 3 {
+		t.Error("RemainingCapacities reordered its input")
+	}
+}
+
+func TestEfficiencyScoreNearOptimalBeatsWasteful(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 600}, {ID: 2, Amount: 400},
+		{ID: 3, Amount: 700}, {ID: 4, Amount: 300},
+	}
+	// Óptimo: dos certificados llenos
+	optimal := []Certificate{
+		{ID: 1, Amount: 1000, Orders: []Order{orders[0], orders[1]}},
+		{ID: 2, Amount: 1000, Orders: []Order{orders[2], orders[3]}},
+	}
+	// Derrochador: una orden por certificado
+	var wasteful []Certificate
+	for i, order := range orders {
+		wasteful = append(wasteful, Certificate{ID: i + 1, Amount: order.Amount, Orders: []Order{order}})
+	}
+
+	best := EfficiencyScore(optimal, orders, 1000)
+	worst := EfficiencyScore(wasteful, orders, 1000)
+	if best != 1 {
+		t.Errorf("optimal packing scores %v, want 1", best)
+	}
+	// Llenado 0.5 y cantidad 2/4
+	if worst != 0.5 {
+		t.Errorf("wasteful packing scores %v, want 0.5", worst)
+	}
+	if EfficiencyScore(nil, orders, 1000) != 0 {
+		t.Error("no certificates should score 0")
+	}
+}
+
+func TestTopCertificatesByAmountTopTwo(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 300},
+		{ID: 2, Amount: 900},
+		{ID: 3, Amount: 150},
+		{ID: 4, Amount: 900},
+		{ID: 5, Amount: 600},
+	}
+
+	top := TopCertificatesByAmount(certs, 2)
+	// Empate en 900: gana el menor ID
+	if len(top) != 2 || top[0].ID != 2 || top[1].ID != 4 {
+		t.Errorf("top 2 = %+v, want certificates 2 and 4", top)
+	}
+	top[0].Amount = 0
+	if certs[1].Amount != 900 {
+		t.Error("TopCertificatesByAmount returned certificates that alias the input")
+	}
+
+	if all := TopCertificatesByAmount(certs, 10); len(all) != 5 || all[4].ID != 3 {
+		t.Errorf("k larger than the set = %+v, want all five ending with certificate 3", all)
+	}
+}
+
+func TestTierReportKnownBoundaries(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 50},
+		{ID: 2, Amount: 99.99},
+		{ID: 3, Amount: 100}, // El límite pertenece al tramo que empieza en él
+		{ID: 4, Amount: 499},
+		{ID: 5, Amount: 500},
+		{ID: 6, Amount: 1000},
+		{ID: 7, Amount: 2500},
+	}
+
+	got := TierReport(orders, []float64{1000, 100, 500}) // Desordenados a propósito
+	want := []AmountTier{
+		{Lower: math.Inf(-1), Upper: 100, Count: 2, Total: 149.99},
+		{Lower: 100, Upper: 500, Count: 2, Total: 599},
+		{Lower: 500, Upper: 1000, Count: 1, Total: 500},
+		{Lower: 1000, Upper: math.Inf(1), Count: 2, Total: 3500},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TierReport = %+v, want %+v", got, want)
+	}
+}
+
+func TestGiniCoefficientEqualAndSkewed(t *testing.T) {
+	equal := []Certificate{{ID: 1, Amount: 500}, {ID: 2, Amount: 500}, {ID: 3, Amount: 500}, {ID: 4, Amount: 500}}
+	if g := GiniCoefficient(equal); math.Abs(g) > 1e-12 {
+		t.Errorf("GiniCoefficient of equal certificates = %v, want 0", g)
+	}
+
+	// Uno concentra casi todo: el máximo posible con 10 certificados es 0.9
+	skewed := []Certificate{{ID: 1, Amount: 1000000}}
+	for i := range 9 {
+		skewed = append(skewed, Certificate{ID: i + 2, Amount: 1})
+	}
+	if g := GiniCoefficient(skewed); g < 0.85 || g > 0.9 {
+		t.Errorf("GiniCoefficient of a skewed set = %v, want close to 0.9", g)
+	}
+
+	if g := GiniCoefficient(nil); g != 0 {
+		t.Errorf("GiniCoefficient(nil) = %v, want 0", g)
+	}
+}
+
+func TestPackingRatioFFDNearOne(t *testing.T) {
+	// Muchas órdenes chicas respecto del límite: FFD queda muy cerca de la cota
+	var small []Order
+	for i := range 2000 {
+		small = append(small, Order{ID: i + 1, Amount: float64(10 + i*53%90), MerchantID: i%20 + 1})
+	}
+	result := GenerateCertificates(small, PackOptions{Limit: 5000})
+	ratio := PackingRatio(result.Certificates, small, 5000)
+	if ratio < 1 || ratio > 1.05 {
+		t.Errorf("PackingRatio = %v, want in [1, 1.05]", ratio)
+	}
+
+	// Pares que suman exactamente el límite: con toda la primera fase el empaquetado es óptimo
+	var pairs []Order
+	for i := range 10 {
+		pairs = append(pairs, Order{ID: 2*i + 1, Amount: 600, MerchantID: 1}, Order{ID: 2*i + 2, Amount: 400, MerchantID: 2})
+	}
+	result = GenerateCertificates(pairs, PackOptions{Limit: 1000, MainPhaseCertificates: 10})
+	if ratio := PackingRatio(result.Certificates, pairs, 1000); ratio != 1 {
+		t.Errorf("PackingRatio of exact pairs = %v, want 1", ratio)
+	}
+}
+
+func TestApproxPercentileCloseToExact(t *testing.T) {
+	// Montos con distribución lognormal, como los de órdenes reales
+	rng := rand.New(rand.NewSource(3))
+	values := make([]float64, 20000)
+	for i := range values {
+		values[i] = math.Exp(8 + 0.7*rng.NormFloat64())
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	iqr := percentile(sorted, 75) - percentile(sorted, 25)
+
+	// La cota documentada, fuera de las colas donde P² tiene menos observaciones
+	for _, p := range []float64{10, 25, 50, 75, 90} {
+		approx := NewApproxPercentile(p)
+		for _, v := range values {
+			approx.Add(v)
+		}
+		exact := percentile(sorted, p)
+		if diff := math.Abs(approx.Value() - exact); diff > 0.01*iqr {
+			t.Errorf("P%v: approx %.2f, exact %.2f; error %.2f exceeds 1%% of the IQR (%.2f)",
+				p, approx.Value(), exact, diff, 0.01*iqr)
+		}
+	}
+}
+
+func TestApproxPercentileExactWithFewValues(t *testing.T) {
+	approx := NewApproxPercentile(50)
+	if v := approx.Value(); v != 0 {
+		t.Errorf("Value with no data = %v, want 0", v)
+	}
+	for _, v := range []float64{40, 10, 30} {
+		approx.Add(v)
+	}
+	if v := approx.Value(); v != 30 {
+		t.Errorf("median of 40, 10, 30 = %v, want 30", v)
+	}
+}
+
+func TestThroughputInjectedDuration(t *testing.T) {
+	tests := []struct {
+		orders int
+		d      time.Duration
+		want   float64
+	}{
+		{2_100_000, 3 * time.Second, 700_000},
+		{500, 250 * time.Millisecond, 2000},
+		{10, 0, 0},
+		{10, -time.Second, 0},
+	}
+	for _, tt := range tests {
+		if got := Throughput(tt.orders, tt.d); got != tt.want {
+			t.Errorf("Throughput(%d, %v) = %v, want %v", tt.orders, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestCorrFillVsOrderCountSign(t *testing.T) {
+	const limit = 1000
+	// withOrders arma un certificado de n órdenes de amount cada una
+	withOrders := func(id, n int, amount float64) Certificate {
+		cert := Certificate{ID: id}
+		for i := range n {
+			cert.Orders = append(cert.Orders, Order{ID: id*100 + i, Amount: amount, MerchantID: 1})
+			cert.Amount += amount
+		}
+		return cert
+	}
+
+	// Órdenes de igual monto: más órdenes, más llenado, en relación exactamente lineal
+	positive := []Certificate{withOrders(1, 2, 100), withOrders(2, 5, 100), withOrders(3, 8, 100)}
+	if r := CorrFillVsOrderCount(positive, limit); math.Abs(r-1) > 1e-12 {
+		t.Errorf("proportional fill: r = %v, want 1", r)
+	}
+
+	// Muchas órdenes chicas quedan menos llenas que pocas grandes
+	negative := []Certificate{withOrders(1, 1, 950), withOrders(2, 3, 300), withOrders(3, 10, 40), withOrders(4, 20, 15)}
+	if r := CorrFillVsOrderCount(negative, limit); r >= 0 {
+		t.Errorf("many small orders filling less: r = %v, want negative", r)
+	}
+
+	// Misma cantidad de órdenes en todos: sin variación no hay correlación
+	if r := CorrFillVsOrderCount([]Certificate{withOrders(1, 3, 100), withOrders(2, 3, 300)}, limit); r != 0 {
+		t.Errorf("constant order count: r = %v, want 0", r)
+	}
+}
+
+func TestMinMarginKnownAndValidPacking(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 820},
+		{ID: 2, Amount: 975.5},
+		{ID: 3, Amount: 400, Limit: 450},        // Tramo con límite propio: margen 50
+		{ID: 4, Amount: 1800, IsOverflow: true}, // Excepción: no cuenta
+	}
+	if got := MinMargin(certs, 1000); got != 24.5 {
+		t.Errorf("MinMargin = %v, want 24.5", got)
+	}
+
+	certs[2].Amount = 460
+	if got := MinMargin(certs, 1000); got != -10 {
+		t.Errorf("MinMargin with certificate 3 over its own limit = %v, want -10", got)
+	}
+
+	var orders []Order
+	for i := range 500 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(20 + i*97%480), MerchantID: i%25 + 1})
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: 5000})
+	if got := MinMargin(result.Certificates, 5000); got < 0 {
+		t.Errorf("MinMargin of a valid packing = %v, want non-negative", got)
+	}
+}
+
+func TestMerchantsPerCertStatsDistinctCounts(t *testing.T) {
+	// merchants arma un certificado con una orden de 10 por cada comerciante indicado
+	merchants := func(id int, merchantIDs ...int) Certificate {
+		cert := Certificate{ID: id}
+		for i, m := range merchantIDs {
+			cert.Orders = append(cert.Orders, Order{ID: id*10 + i, Amount: 10, MerchantID: m})
+			cert.Amount += 10
+		}
+		return cert
+	}
+	// Comerciantes distintos: 2, 1, 4 y 2; las órdenes repetidas no suman
+	certs := []Certificate{
+		merchants(1, 1, 1, 2),
+		merchants(2, 3, 3, 3),
+		merchants(3, 4, 1, 2, 3, 4),
+		merchants(4, 5, 6),
+	}
+	got := MerchantsPerCertStats(certs)
+	// Ordenadas: 1 2 2 4; P90 interpola entre 2 y 4 en el índice 2.7
+	want := MerchantsPerCertSummary{Min: 1, P50: 2, P90: 3.4, Max: 4}
+	if got.Min != want.Min || got.P50 != want.P50 || math.Abs(got.P90-want.P90) > 1e-9 || got.Max != want.Max {
+		t.Errorf("MerchantsPerCertStats = %+v, want %+v", got, want)
+	}
+
+	// Sobre un empaquetado con tope de comerciantes, el máximo lo respeta
+	var orders []Order
+	for i := range 90 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(30 + i*11%70), MerchantID: i%9 + 1})
+	}
+	result := GenerateCertificates(orders, PackOptions{Limit: 600, MaxMerchantsPerCert: 2})
+	if stats := MerchantsPerCertStats(result.Certificates); stats.Max > 2 || stats.Min < 1 {
+		t.Errorf("MaxMerchantsPerCert 2 packing: %+v, want counts between 1 and 2", stats)
+	}
+}
+
+func TestSensitivityToLimitLargerLimitFewerCertificates(t *testing.T) {
+	// Poco más de $6M en total. El límite mayor supera al por defecto y el
+	// empaquetado tiene que respetarlo en lugar de recortarlo a $500,000
+	var orders []Order
+	for i := range 200 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(30000 + i*7%20000), MerchantID: i%20 + 1})
+	}
+	const small, large = 500000, 1000000
+
+	counts := SensitivityToLimit(orders, []float64{small, large, small})
+	if len(counts) != 2 {
+		t.Errorf("got %d entries, want one per distinct limit: %v", len(counts), counts)
+	}
+	if counts[large] >= counts[small] {
+		t.Errorf("limit %d gives %d certificates, want fewer than the %d of limit %d",
+			large, counts[large], counts[small], small)
+	}
+	// Con el doble de límite alcanza cerca de la mitad de certificados
+	if counts[large] > counts[small]/2+2 {
+		t.Errorf("limit %d gives %d certificates, want about half of %d", large, counts[large], counts[small])
+	}
+
+	// Con un límite menor que la orden más grande la cuenta no cubriría todas
+	const tooSmall = 31000
+	counts = SensitivityToLimit(orders, []float64{tooSmall, small})
+	if _, ok := counts[tooSmall]; ok || len(counts) != 1 {
+		t.Errorf("counts = %v, want only limit %d since some orders exceed %d", counts, small, tooSmall)
+	}
+
+	result := GenerateCertificates(orders, PackOptions{Limit: large})
+	if err := VerifyCertificates(orders, result.Certificates, large); err != nil {
+		t.Fatal(err)
+	}
+	if MaxCertificateAmount(result.Certificates) <= small {
+		t.Errorf("no certificate exceeds $%d with a limit of $%d", small, large)
+	}
+}
+
+func TestMaxAccumulationDriftTinyOrders(t *testing.T) {
+	// 100.000 órdenes de un centavo en un solo certificado
+	orders := make([]Order, 100000)
+	for i := range orders {
+		orders[i] = Order{ID: i + 1, Amount: 0.01, MerchantID: i%50 + 1}
+	}
+	pack := func(kahan bool) []Certificate {
+		result := GenerateCertificates(orders, PackOptions{Limit: 5000, KahanSummation: kahan})
+		if len(result.Certificates) != 1 {
+			t.Fatalf("got %d certificates, want all orders in one", len(result.Certificates))
+		}
+		return result.Certificates
+	}
+
+	naive := MaxAccumulationDrift(pack(false))
+	if naive == 0 {
+		t.Fatal("no drift measured on 100,000 naive additions of 0.01")
+	}
+	if kahan := MaxAccumulationDrift(pack(true)); kahan >= naive {
+		t.Errorf("drift with Kahan summation %g, want below the naive %g", kahan, naive)
+	}
+
+	// Un certificado declarado con un centavo de más se mide tal cual
+	off := []Certificate{{ID: 1, Amount: 30.01, Orders: []Order{{ID: 1, Amount: 10}, {ID: 2, Amount: 20}}}}
+	if drift := MaxAccumulationDrift(off); math.Abs(drift-0.01) > 1e-9 {
+		t.Errorf("MaxAccumulationDrift = %v, want 0.01", drift)
+	}
+}
+
+func TestMinLimitForCertCountAboveDefaultLimit(t *testing.T) {
+	// $2M en 40 órdenes iguales: para 3 certificados hacen falta 14 órdenes en
+	// uno, así que el límite mínimo posible es $700,000, por encima del límite
+	// por defecto
+	var orders []Order
+	for i := range 40 {
+		orders = append(orders, Order{ID: i + 1, Amount: 50000, MerchantID: i%8 + 1})
+	}
+	const k = 3
+
+	if limit := MinLimitForCertCount(orders, k); limit != 700000 {
+		t.Errorf("MinLimitForCertCount = %.2f, want 700000", limit)
+	}
+
+	// Con montos dispares el resultado cumple y un centavo menos ya no
+	for i := range orders {
+		orders[i].Amount = float64(20000 + i*7919%60000)
+	}
+	limit := MinLimitForCertCount(orders, k)
+	count := func(limit float64) int {
+		return len(GenerateCertificates(orders, PackOptions{Limit: limit}).Certificates)
+	}
+	if n := count(limit); n > k {
+		t.Errorf("limit %.2f gives %d certificates, want at most %d", limit, n, k)
+	}
+	if n := count(limit - 0.01); n <= k {
+		t.Errorf("limit %.2f gives %d certificates, want more than %d", limit-0.01, n, k)
+	}
+}
+
+func TestMinLimitForCertCountIsLargestOrder(t *testing.T) {
+	// 700 | 400+300: con dos certificados la orden más grande fija el límite
+	orders := []Order{
+		{ID: 1, Amount: 700, MerchantID: 1},
+		{ID: 2, Amount: 400, MerchantID: 2},
+		{ID: 3, Amount: 300, MerchantID: 3},
+	}
+	if limit := MinLimitForCertCount(orders, 2); limit != 700 {
+		t.Errorf("MinLimitForCertCount = %.2f, want 700", limit)
+	}
+
+	// Una fracción de centavo por encima no cabe en $700: si la orden quedara
+	// sin colocar, el resto entraría en un solo certificado y $700 parecería
+	// alcanzar
+	orders[0].Amount = 700.00004
+	if limit := MinLimitForCertCount(orders, 2); limit != 700.01 {
+		t.Errorf("MinLimitForCertCount = %.2f, want 700.01", limit)
+	}
+}
+
+func TestFillBucketCountsHalfFullInFiftyBucket(t *testing.T) {
+	const limit = 1000
+	certs := []Certificate{
+		{ID: 1, Amount: 500},  // Lleno a la mitad
+		{ID: 2, Amount: 300},  // 0.3*100 no debe caer en 20
+		{ID: 3, Amount: 950},  // 95%
+		{ID: 4, Amount: 1000}, // Exactamente lleno: el tramo que termina en 100%
+		{ID: 5, Amount: 1100}, // Excede el límite
+		{ID: 6, Amount: 540},
+		{ID: 7, Amount: 0},
+	}
+
+	got := FillBucketCounts(certs, limit, 10)
+	want := map[int]int{0: 1, 30: 1, 50: 2, 90: 2, 110: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FillBucketCounts = %v, want %v", got, want)
+	}
+
+	if got := FillBucketCounts(certs, 0, 10); got != nil {
+		t.Errorf("FillBucketCounts with limit 0 = %v, want nil", got)
+	}
+	if got := FillBucketCounts(certs, limit, 0); got != nil {
+		t.Errorf("FillBucketCounts with bucketPct 0 = %v, want nil", got)
+	}
+}
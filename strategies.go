@@ -0,0 +1,329 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// RoundRobinCertificates reparte las órdenes, en el orden recibido, entre una
+// cantidad fija de certificados usando round-robin ponderado: el certificado i
+// recibe weights[i] órdenes consecutivas antes de pasar al siguiente. Si una
+// orden no cabe en el certificado que le toca, se prueba con los siguientes; las
+// que no caben en ninguno se devuelven en Result.Unplaceable. Con weights nil
+// todos los certificados tienen peso 1 y con limit <= 0 se usa el límite por
+// defecto. Los certificados vacíos se omiten.
+func RoundRobinCertificates(orders []Order, limit float64, count int, weights []int) (Result, error) {
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	if count < 1 {
+		return Result{}, fmt.Errorf("cantidad de certificados inválida: %d", count)
+	}
+	if weights == nil {
+		weights = make([]int, count)
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	if len(weights) != count {
+		return Result{}, fmt.Errorf("se esperaban %d pesos, se recibieron %d", count, len(weights))
+	}
+	for i, w := range weights {
+		if w < 1 {
+			return Result{}, fmt.Errorf("peso inválido %d para el certificado %d", w, i+1)
+		}
+	}
+
+	maxAmount := limitWithTolerance(limit, &PackOptions{})
+	builders := make([]certificateBuilder, count)
+	var result Result
+
+	slot := 0
+	credit := weights[0] // Órdenes que aún puede recibir el certificado actual en esta ronda
+	for _, order := range orders {
+		placed := false
+		for tried := 0; tried < count; tried++ {
+			if builders[slot].Amount+order.Amount <= maxAmount {
+				builders[slot].add(order)
+				placed = true
+				break
+			}
+			// La orden desbordaría este certificado: pasar al siguiente
+			slot = (slot + 1) % count
+			credit = weights[slot]
+		}
+		if !placed {
+			result.Unplaceable = append(result.Unplaceable, order)
+			continue
+		}
+
+		credit--
+		if credit == 0 {
+			slot = (slot + 1) % count
+			credit = weights[slot]
+		}
+	}
+
+	for i, builder := range builders {
+		if len(builder.Orders) == 0 {
+			continue
+		}
+		result.Certificates = append(result.Certificates, Certificate{
+			ID:     i + 1,
+			Amount: builder.Amount,
+			Orders: builder.Orders,
+		})
+	}
+	return result, nil
+}
+
+// GenerateCertificatesTargetCount reparte las órdenes en exactamente count
+// certificados buscando minimizar el monto del certificado más cargado, con la
+// heurística LPT (Longest Processing Time) de planificación multiprocesador:
+// las órdenes se recorren de mayor a menor y cada una va al certificado con
+// menor monto acumulado. No aplica ningún límite de monto; el máximo obtenido
+// puede consultarse con MaxCertificateAmount.
+func GenerateCertificatesTargetCount(orders []Order, count int) []Certificate {
+	if count < 1 {
+		return nil
+	}
+
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return amountDescending(sorted[i], sorted[j])
+	})
+
+	certs := make(certificateHeap, count)
+	for i := range certs {
+		certs[i] = &Certificate{ID: i + 1}
+	}
+	heap.Init(&certs)
+	for _, order := range sorted {
+		lightest := certs[0]
+		lightest.Orders = append(lightest.Orders, order)
+		lightest.Amount += order.Amount
+		heap.Fix(&certs, 0)
+	}
+
+	result := make([]Certificate, count)
+	for _, cert := range certs {
+		result[cert.ID-1] = *cert
+	}
+	return result
+}
+
+// MinimizeMaxAmount reparte las órdenes en count certificados de modo que el más
+// cargado tenga el menor monto posible, para repartir el riesgo en lugar de
+// minimizar la cantidad de certificados. Es un nombre orientado al objetivo
+// para GenerateCertificatesTargetCount, que ya aplica la heurística LPT: su
+// máximo nunca supera 4/3 del óptimo.
+func MinimizeMaxAmount(orders []Order, count int) []Certificate {
+	return GenerateCertificatesTargetCount(orders, count)
+}
+
+// certificateHeap es un min-heap de certificados por monto, desempatando por ID
+type certificateHeap []*Certificate
+
+func (h certificateHeap) Len() int { return len(h) }
+func (h certificateHeap) Less(i, j int) bool {
+	if h[i].Amount != h[j].Amount {
+		return h[i].Amount < h[j].Amount
+	}
+	return h[i].ID < h[j].ID
+}
+func (h certificateHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *certificateHeap) Push(x any)   { *h = append(*h, x.(*Certificate)) }
+func (h *certificateHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// CertificateTier describe un tipo de certificado: su límite de monto y cuántos
+// pueden emitirse (0 = sin tope)
+type CertificateTier struct {
+	Limit float64
+	Count int
+}
+
+// GenerateTieredCertificates empaqueta las órdenes con First-Fit-Decreasing en
+// certificados de varios tramos. Los tramos se usan de mayor a menor límite: un
+// certificado de un tramo menor solo se abre cuando los del mayor se agotaron
+// (o la orden no cabe en su límite) y la orden no entra en ninguno ya abierto.
+// Cada certificado lleva en Limit el límite de su tramo. Las órdenes que no
+// caben en ningún certificado disponible se devuelven en Result.Unplaceable.
+func GenerateTieredCertificates(orders []Order, tiers []CertificateTier) (Result, error) {
+	if len(tiers) == 0 {
+		return Result{}, fmt.Errorf("se requiere al menos un tramo de certificados")
+	}
+	for i, tier := range tiers {
+		if tier.Limit <= 0 || tier.Count < 0 {
+			return Result{}, fmt.Errorf("tramo %d inválido: límite $%.2f, cantidad %d", i+1, tier.Limit, tier.Count)
+		}
+	}
+	sortedTiers := append([]CertificateTier(nil), tiers...)
+	sort.SliceStable(sortedTiers, func(i, j int) bool {
+		return sortedTiers[i].Limit > sortedTiers[j].Limit
+	})
+
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return amountDescending(sorted[i], sorted[j])
+	})
+
+	var opts PackOptions
+	var builders []certificateBuilder
+	var limits, maxAmounts []float64 // Límite del tramo de cada builder, sin y con tolerancia
+	opened := make([]int, len(sortedTiers))
+	var result Result
+	for _, order := range sorted {
+		placed := false
+		for i := range builders {
+			if builders[i].fits(order, maxAmounts[i], &opts) {
+				builders[i].add(order)
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		for t, tier := range sortedTiers {
+			if tier.Count > 0 && opened[t] >= tier.Count {
+				continue
+			}
+			if !(order.Amount <= limitWithTolerance(tier.Limit, &opts)) {
+				continue
+			}
+			builder := newCertificateBuilder(&opts)
+			builder.add(order)
+			builders = append(builders, builder)
+			limits = append(limits, tier.Limit)
+			maxAmounts = append(maxAmounts, limitWithTolerance(tier.Limit, &opts))
+			opened[t]++
+			placed = true
+			break
+		}
+		if !placed {
+			result.Unplaceable = append(result.Unplaceable, order)
+		}
+	}
+
+	for i, builder := range builders {
+		result.Certificates = append(result.Certificates, Certificate{
+			ID:     i + 1,
+			Amount: builder.Amount,
+			Orders: builder.Orders,
+			Limit:  limits[i],
+		})
+	}
+	return result, nil
+}
+
+// maxOptimalOrders es la mayor cantidad de órdenes que acepta OptimalPacking;
+// el costo de la búsqueda exacta crece exponencialmente con ella
+const maxOptimalOrders = 20
+
+// OptimalPacking devuelve un empaquetado con la mínima cantidad posible de
+// certificados, pensado para validar las heurísticas en casos chicos. Usa
+// ramificación y poda sobre las órdenes de mayor a menor, partiendo del
+// resultado de First-Fit-Decreasing y descartando ramas que no pueden mejorarlo
+// según la cota de MinCertificatesLowerBound. Devuelve un error con más de 20
+// órdenes o si alguna excede el límite por sí misma.
+func OptimalPacking(orders []Order, limit float64) ([]Certificate, error) {
+	if len(orders) > maxOptimalOrders {
+		return nil, fmt.Errorf("la búsqueda exacta admite hasta %d órdenes, se recibieron %d",
+			maxOptimalOrders, len(orders))
+	}
+	if limit <= 0 {
+		limit = defaultCertificateLimit
+	}
+	maxAmount := limit + defaultEpsilon
+
+	sorted := append([]Order(nil), orders...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return amountDescending(sorted[i], sorted[j])
+	})
+	for _, order := range sorted {
+		if !(order.Amount <= maxAmount) {
+			return nil, fmt.Errorf("orden %d %w: $%.2f > $%.2f", order.ID, ErrOrderExceedsLimit, order.Amount, limit)
+		}
+	}
+
+	best := firstFitDecreasing(sorted, PackOptions{Limit: limit}, 1)
+	lowerBound := MinCertificatesLowerBound(sorted, limit)
+	if len(best) <= lowerBound {
+		return best, nil
+	}
+
+	// remaining[i] es la suma de las órdenes desde i, para acotar cuántos
+	// certificados más necesita una rama
+	remaining := make([]float64, len(sorted)+1)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		remaining[i] = remaining[i+1] + sorted[i].Amount
+	}
+
+	bestCount := len(best)
+	var bestAssignment []int
+	assignment := make([]int, len(sorted))
+	var loads []float64
+	var search func(i int)
+	search = func(i int) {
+		if bestCount <= lowerBound {
+			return
+		}
+		if i == len(sorted) {
+			bestCount = len(loads)
+			bestAssignment = append(bestAssignment[:0], assignment...)
+			return
+		}
+
+		var free float64
+		for _, load := range loads {
+			free += limit - load
+		}
+		// Sin la tolerancia, sumas que caen justo en el límite pero con ruido de
+		// punto flotante pedirían un certificado de más y podarían el óptimo
+		if extra := math.Ceil((remaining[i] - free - defaultEpsilon) / limit); len(loads)+max(int(extra), 0) >= bestCount {
+			return
+		}
+
+		order := sorted[i]
+		for b, load := range loads {
+			if load+order.Amount > maxAmount || slices.Contains(loads[:b], load) {
+				// Dos certificados con la misma carga son intercambiables: basta el primero
+				continue
+			}
+			assignment[i] = b
+			loads[b] = load + order.Amount
+			search(i + 1)
+			loads[b] = load
+		}
+		if len(loads)+1 < bestCount {
+			assignment[i] = len(loads)
+			loads = append(loads, order.Amount)
+			search(i + 1)
+			loads = loads[:len(loads)-1]
+		}
+	}
+	search(0)
+
+	if bestAssignment == nil {
+		return best, nil
+	}
+	certificates := make([]Certificate, bestCount)
+	for i := range certificates {
+		certificates[i].ID = i + 1
+	}
+	for i, order := range sorted {
+		cert := &certificates[bestAssignment[i]]
+		cert.Orders = append(cert.Orders, order)
+		cert.Amount += order.Amount
+	}
+	return certificates, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestRoundRobinCertificatesEqualWeightsSpreadEvenly(t *testing.T) {
+	var orders []Order
+	for i := range 40 {
+		orders = append(orders, Order{ID: i + 1, Amount: float64(50 + i%5*10), MerchantID: i%3 + 1})
+	}
+
+	result, err := RoundRobinCertificates(orders, 1000, 4, []int{1, 1, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Certificates) != 4 || len(result.Unplaceable) != 0 {
+		t.Fatalf("got %d certificates and %d unplaceable, want 4 and 0",
+			len(result.Certificates), len(result.Unplaceable))
+	}
+	for _, cert := range result.Certificates {
+		if len(cert.Orders) != 10 {
+			t.Errorf("certificate %d holds %d orders, want 10", cert.ID, len(cert.Orders))
+		}
+		if cert.Amount > 1000 {
+			t.Errorf("certificate %d holds %.2f, over the limit", cert.ID, cert.Amount)
+		}
+	}
+}
+
+func TestRoundRobinCertificatesDefaultLimit(t *testing.T) {
+	// Con limit 0 se usa el límite por defecto en lugar de rechazar todo
+	orders := []Order{{ID: 1, Amount: 300000}, {ID: 2, Amount: 150000}, {ID: 3, Amount: 100000}}
+	result, err := RoundRobinCertificates(orders, 0, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Unplaceable) != 0 {
+		t.Errorf("Unplaceable = %v, want none under the default limit", result.Unplaceable)
+	}
+	for _, cert := range result.Certificates {
+		if cert.Amount > defaultCertificateLimit {
+			t.Errorf("certificate %d holds %.2f, over the default limit", cert.ID, cert.Amount)
+		}
+	}
+}
+
+func TestRoundRobinCertificatesToleratesFloatNoise(t *testing.T) {
+	// 0.1 + 0.2 suma 0.30000000000000004: ruido de punto flotante, no un exceso real
+	orders := []Order{{ID: 1, Amount: 0.1}, {ID: 2, Amount: 0.2}, {ID: 3, Amount: 0.0001}}
+	result, err := RoundRobinCertificates(orders, 0.3, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Certificates) != 1 || len(result.Certificates[0].Orders) != 2 {
+		t.Fatalf("got %+v, want orders 1 and 2 in a single certificate", result.Certificates)
+	}
+	// Un exceso mayor que la tolerancia sí se detecta
+	if len(result.Unplaceable) != 1 || result.Unplaceable[0].ID != 3 {
+		t.Errorf("Unplaceable = %v, want order 3", result.Unplaceable)
+	}
+}
+
+func TestGenerateCertificatesTargetCountFourBalanced(t *testing.T) {
+	// 440 en total: LPT logra cuatro certificados de 110 (90+20, 80+30, 70+40, 60+50)
+	var orders []Order
+	for i, amount := range []float64{50, 90, 20, 70, 40, 80, 30, 60} {
+		orders = append(orders, Order{ID: i + 1, Amount: amount, MerchantID: 1})
+	}
+
+	certs := GenerateCertificatesTargetCount(orders, 4)
+	if len(certs) != 4 {
+		t.Fatalf("got %d certificates, want 4", len(certs))
+	}
+	for _, cert := range certs {
+		if cert.Amount != 110 || len(cert.Orders) != 2 {
+			t.Errorf("certificate %d: %.2f in %d orders, want 110 in 2", cert.ID, cert.Amount, len(cert.Orders))
+		}
+	}
+	if got := MaxCertificateAmount(certs); got != 110 {
+		t.Errorf("MaxCertificateAmount = %v, want 110", got)
+	}
+	if err := VerifyCertificates(orders, certs, 110); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestGenerateTieredCertificatesFillsLargeTierFirst(t *testing.T) {
+	// 13 órdenes de 90K: caben 5 por certificado grande y 1 por certificado chico
+	var orders []Order
+	for i := range 13 {
+		orders = append(orders, Order{ID: i + 1, Amount: 90000, MerchantID: i%3 + 1})
+	}
+	tiers := []CertificateTier{
+		{Limit: 100000},           // Chicos, sin tope; se pasan primero a propósito
+		{Limit: 500000, Count: 2}, // Grandes
+	}
+
+	result, err := GenerateTieredCertificates(orders, tiers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Certificates) != 5 || len(result.Unplaceable) != 0 {
+		t.Fatalf("got %d certificates and %d unplaceable, want 5 and 0",
+			len(result.Certificates), len(result.Unplaceable))
+	}
+	for i, cert := range result.Certificates {
+		wantLimit, wantOrders := 100000.0, 1
+		if i < 2 {
+			wantLimit, wantOrders = 500000, 5
+		}
+		if cert.Limit != wantLimit || len(cert.Orders) != wantOrders {
+			t.Errorf("certificate %d: limit %.0f with %d orders, want %.0f with %d",
+				cert.ID, cert.Limit, len(cert.Orders), wantLimit, wantOrders)
+		}
+		if cert.Amount > cert.Limit {
+			t.Errorf("certificate %d holds %.2f, over its tier limit", cert.ID, cert.Amount)
+		}
+	}
+}
+
+func TestOptimalPackingBeatsFFD(t *testing.T) {
+	// FFD arma 5+4 | 3+3+3 | 2, pero 5+3+2 y 4+3+3 llenan dos certificados justos
+	orders := []Order{
+		{ID: 1, Amount: 5, MerchantID: 1},
+		{ID: 2, Amount: 4, MerchantID: 2},
+		{ID: 3, Amount: 3, MerchantID: 3},
+		{ID: 4, Amount: 3, MerchantID: 4},
+		{ID: 5, Amount: 3, MerchantID: 5},
+		{ID: 6, Amount: 2, MerchantID: 6},
+	}
+	const limit = 10
+
+	ffd := GenerateCertificates(orders, PackOptions{Limit: limit, DisableBalancePhase: true})
+	if len(ffd.Certificates) != 3 {
+		t.Fatalf("FFD used %d certificates, want 3 for this input to be adversarial", len(ffd.Certificates))
+	}
+
+	optimal, err := OptimalPacking(orders, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(optimal) != 2 {
+		t.Errorf("OptimalPacking used %d certificates, want 2", len(optimal))
+	}
+	if err := VerifyCertificates(orders, optimal, limit); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestOptimalPackingSumsExactlyOnLimit(t *testing.T) {
+	// El mismo caso en décimas: 0.5+0.3+0.2 y 0.4+0.3+0.3 suman 1 salvo por
+	// el ruido de punto flotante, que no debe costar un certificado de más
+	orders := []Order{
+		{ID: 1, Amount: 0.5, MerchantID: 1},
+		{ID: 2, Amount: 0.4, MerchantID: 2},
+		{ID: 3, Amount: 0.3, MerchantID: 3},
+		{ID: 4, Amount: 0.3, MerchantID: 4},
+		{ID: 5, Amount: 0.3, MerchantID: 5},
+		{ID: 6, Amount: 0.2, MerchantID: 6},
+	}
+	const limit = 1
+
+	if bound := MinCertificatesLowerBound(orders, limit); bound != 2 {
+		t.Errorf("MinCertificatesLowerBound = %d, want 2", bound)
+	}
+	optimal, err := OptimalPacking(orders, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(optimal) != 2 {
+		t.Errorf("OptimalPacking used %d certificates, want 2", len(optimal))
+	}
+	if err := VerifyCertificates(orders, optimal, limit); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
+
+func TestOptimalPackingRejectsLargeInputs(t *testing.T) {
+	var orders []Order
+	for i := range maxOptimalOrders + 1 {
+		orders = append(orders, Order{ID: i + 1, Amount: 1, MerchantID: 1})
+	}
+	if _, err := OptimalPacking(orders, 10); err == nil {
+		t.Errorf("OptimalPacking accepted %d orders", len(orders))
+	}
+	if _, err := OptimalPacking(orders[:maxOptimalOrders], 10); err != nil {
+		t.Errorf("OptimalPacking rejected %d orders: %v", maxOptimalOrders, err)
+	}
+	if _, err := OptimalPacking([]Order{{ID: 1, Amount: 11, MerchantID: 1}}, 10); !errors.Is(err, ErrOrderExceedsLimit) {
+		t.Errorf("oversized order: err = %v, want %v", err, ErrOrderExceedsLimit)
+	}
+}
+
+func TestMinimizeMaxAmountBeatsNaiveRoundRobin(t *testing.T) {
+	// Las grandes caen cada tres posiciones: repartir en turno las junta a todas
+	var orders []Order
+	for i, amount := range []float64{900, 20, 30, 800, 40, 10, 700, 50, 60, 600, 30, 20} {
+		orders = append(orders, Order{ID: i + 1, Amount: amount, MerchantID: i%4 + 1})
+	}
+	const count = 3
+
+	naive := make([]float64, count)
+	for i, order := range orders {
+		naive[i%count] += order.Amount
+	}
+	naiveMax := slices.Max(naive)
+
+	certs := MinimizeMaxAmount(orders, count)
+	if len(certs) != count {
+		t.Fatalf("got %d certificates, want %d", len(certs), count)
+	}
+	var got float64
+	for _, cert := range certs {
+		got = max(got, cert.Amount)
+	}
+	if got >= naiveMax {
+		t.Errorf("max certificate amount %.2f, want below naive round-robin's %.2f", got, naiveMax)
+	}
+	// Cuatro órdenes grandes en tres certificados: dos comparten, y lo mejor
+	// posible es 700+600
+	if got != 1300 {
+		t.Errorf("max certificate amount %.2f, want the optimal 1300", got)
+	}
+	if err := VerifyCertificates(orders, certs, math.Inf(1)); err != nil {
+		t.Errorf("VerifyCertificates: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// amountTolerance es la diferencia máxima aceptada al comparar montos acumulados
+const amountTolerance = 0.005
+
+// VerifyCertificates comprueba que ningún certificado exceda el límite (o su
+// propio Limit, si lo tiene) más la tolerancia de redondeo por defecto (salvo
+// los marcados como IsOverflow), que el monto de cada certificado coincida con la suma
+// de sus órdenes y que cada orden aparezca exactamente una vez. Devuelve el
+// primer problema encontrado, que envuelve a ErrOrderExceedsLimit,
+// ErrCertificateOverflow, ErrDuplicateOrderID o ErrConservationFailed.
+func VerifyCertificates(orders []Order, certs []Certificate, limit float64) error {
+	expected := make(map[int]bool, len(orders))
+	for _, order := range orders {
+		expected[order.ID] = true
+	}
+
+	seen := make(map[int]int, len(orders)) // ID de orden -> ID de certificado
+	for _, cert := range certs {
+		certLimit := limit
+		if cert.Limit > 0 {
+			certLimit = cert.Limit
+		}
+		if !cert.IsOverflow && cert.Amount > certLimit+defaultEpsilon {
+			for _, order := range cert.Orders {
+				if order.Amount > certLimit+defaultEpsilon {
+					return fmt.Errorf("orden %d del certificado %d %w: $%.2f > $%.2f",
+						order.ID, cert.ID, ErrOrderExceedsLimit, order.Amount, certLimit)
+				}
+			}
+			return fmt.Errorf("certificado %d %w: $%.2f > $%.2f",
+				cert.ID, ErrCertificateOverflow, cert.Amount, certLimit)
+		}
+
+		var sum float64
+		for _, order := range cert.Orders {
+			sum += order.Amount
+			if !expected[order.ID] {
+				return fmt.Errorf("%w: certificado %d contiene la orden %d que no está en la entrada",
+					ErrConservationFailed, cert.ID, order.ID)
+			}
+			if other, ok := seen[order.ID]; ok {
+				return fmt.Errorf("%w: orden %d aparece en los certificados %d y %d",
+					ErrDuplicateOrderID, order.ID, other, cert.ID)
+			}
+			seen[order.ID] = cert.ID
+		}
+		if math.Abs(sum-cert.Amount) > amountTolerance {
+			return fmt.Errorf("%w: certificado %d declara $%.2f pero sus órdenes suman $%.2f",
+				ErrConservationFailed, cert.ID, cert.Amount, sum)
+		}
+	}
+
+	for _, order := range orders {
+		if _, ok := seen[order.ID]; !ok {
+			return fmt.Errorf("%w: orden %d no está en ningún certificado", ErrConservationFailed, order.ID)
+		}
+	}
+	return nil
+}
+
+// ValidateOrders comprueba que las órdenes sean aptas para empaquetar: que cada
+// una tenga un ID de comerciante positivo, ya que 0 y los negativos se
+// confundirían con un comerciante real al agrupar. Devuelve el primer problema.
+func ValidateOrders(orders []Order) error {
+	for _, order := range orders {
+		if order.MerchantID <= 0 {
+			return fmt.Errorf("orden %d: ID de comerciante inválido %d", order.ID, order.MerchantID)
+		}
+	}
+	return nil
+}
+
+// certificateJSON y orderJSON describen el formato que acepta
+// ValidateCertificateJSON. Los campos obligatorios son punteros para distinguir
+// un campo ausente de uno en cero.
+type certificateJSON struct {
+	ID         *int
+	Amount     *float64
+	Orders     *[]orderJSON
+	IsOverflow bool
+	Limit      float64
+	CreatedAt  time.Time
+}
+
+type orderJSON struct {
+	ID         *int
+	Amount     *float64
+	MerchantID *int
+	Tags       map[string]string
+	Timestamp  time.Time
+}
+
+// ValidateCertificateJSON comprueba que r contenga un arreglo JSON de
+// certificados con el formato de WriteCertificatesJSON antes de confiar en él:
+//
+//	[{"ID": int, "Amount": número, "Orders": [orden...],
+//	  "IsOverflow": bool opcional, "Limit": número opcional, "CreatedAt": fecha RFC 3339 opcional}]
+//	orden: {"ID": int, "Amount": número, "MerchantID": int,
+//	        "Tags": {string: string} opcional, "Timestamp": fecha RFC 3339 opcional}
+//
+// Rechaza campos desconocidos o de tipo incorrecto, la falta de un campo
+// obligatorio, montos o límites negativos y datos después del arreglo. No
+// verifica el empaquetado en sí; para eso está VerifyCertificates.
+func ValidateCertificateJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var certs []certificateJSON
+	if err := dec.Decode(&certs); err != nil {
+		return fmt.Errorf("JSON de certificados inválido: %w", err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("JSON de certificados inválido: datos después del arreglo")
+	}
+
+	for i, cert := range certs {
+		switch {
+		case cert.ID == nil:
+			return fmt.Errorf("certificado %d: falta el campo ID", i)
+		case cert.Amount == nil:
+			return fmt.Errorf("certificado %d: falta el campo Amount", *cert.ID)
+		case cert.Orders == nil:
+			return fmt.Errorf("certificado %d: falta el campo Orders", *cert.ID)
+		case *cert.Amount < 0:
+			return fmt.Errorf("certificado %d: monto negativo %v", *cert.ID, *cert.Amount)
+		case cert.Limit < 0:
+			return fmt.Errorf("certificado %d: límite negativo %v", *cert.ID, cert.Limit)
+		}
+		for j, order := range *cert.Orders {
+			switch {
+			case order.ID == nil:
+				return fmt.Errorf("certificado %d, orden %d: falta el campo ID", *cert.ID, j)
+			case order.Amount == nil:
+				return fmt.Errorf("certificado %d, orden %d: falta el campo Amount", *cert.ID, *order.ID)
+			case order.MerchantID == nil:
+				return fmt.Errorf("certificado %d, orden %d: falta el campo MerchantID", *cert.ID, *order.ID)
+			case *order.Amount < 0:
+				return fmt.Errorf("certificado %d, orden %d: monto negativo %v", *cert.ID, *order.ID, *order.Amount)
+			}
+		}
+	}
+	return nil
+}
+
+// MissingOrders devuelve, en el orden de entrada, las órdenes cuyo ID no aparece
+// en ningún certificado. Complementa a VerifyCertificates nombrando todas las
+// faltantes en lugar de solo la primera.
+func MissingOrders(orders []Order, certs []Certificate) []Order {
+	assigned := make(map[int]bool)
+	for _, cert := range certs {
+		for _, order := range cert.Orders {
+			assigned[order.ID] = true
+		}
+	}
+
+	var missing []Order
+	for _, order := range orders {
+		if !assigned[order.ID] {
+			missing = append(missing, order)
+		}
+	}
+	return missing
+}
+
+// DigestCertificates calcula un resumen SHA-256 (en hexadecimal) de los
+// certificados y sus órdenes para verificar que un resultado transmitido llegó
+// íntegro. Se calcula sobre una representación canónica, con los certificados
+// y las órdenes ordenados por ID, así que no depende del orden de los slices.
+// No incluye CreatedAt, para que el mismo empaquetado dé el mismo resumen
+// aunque se repita en otro momento.
+func DigestCertificates(certs []Certificate) string {
+	sorted := append([]Certificate(nil), certs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	h := sha256.New()
+	for _, cert := range sorted {
+		fmt.Fprintf(h, "C|%d|%s|%t", cert.ID, strconv.FormatFloat(cert.Amount, 'f', -1, 64), cert.IsOverflow)
+		// Solo si existe, para no cambiar el resumen de certificados sin tramo
+		if cert.Limit > 0 {
+			fmt.Fprintf(h, "|L%s", strconv.FormatFloat(cert.Limit, 'f', -1, 64))
+		}
+		fmt.Fprintln(h)
+
+		orders := append([]Order(nil), cert.Orders...)
+		sort.Slice(orders, func(i, j int) bool {
+			return orders[i].ID < orders[j].ID
+		})
+		for _, order := range orders {
+			fmt.Fprintf(h, "O|%d|%s|%d", order.ID, strconv.FormatFloat(order.Amount, 'f', -1, 64), order.MerchantID)
+			keys := make([]string, 0, len(order.Tags))
+			for key := range order.Tags {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(h, "|%q=%q", key, order.Tags[key])
+			}
+			// Solo si existe, para no cambiar el resumen de órdenes sin Timestamp
+			if !order.Timestamp.IsZero() {
+				fmt.Fprintf(h, "|@%s", order.Timestamp.UTC().Format(time.RFC3339Nano))
+			}
+			fmt.Fprintln(h)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDigestCertificatesIgnoresSliceOrder(t *testing.T) {
+	certs := []Certificate{
+		{ID: 1, Amount: 300, Orders: []Order{{ID: 1, Amount: 200, MerchantID: 1}, {ID: 2, Amount: 100, MerchantID: 2}}},
+		{ID: 2, Amount: 150, Orders: []Order{{ID: 3, Amount: 150, MerchantID: 1, Tags: map[string]string{"region": "sur"}}}},
+		{ID: 3, Amount: 90, Orders: []Order{{ID: 4, Amount: 90, MerchantID: 3}}},
+	}
+	// El mismo conjunto con los certificados y las órdenes en otro orden
+	reordered := []Certificate{
+		certs[2],
+		{ID: 1, Amount: 300, Orders: []Order{certs[0].Orders[1], certs[0].Orders[0]}},
+		certs[1],
+	}
+
+	digest := DigestCertificates(certs)
+	if len(digest) != 64 {
+		t.Errorf("digest %q is not a hex SHA-256", digest)
+	}
+	if got := DigestCertificates(reordered); got != digest {
+		t.Errorf("reordered set digest = %s, want %s", got, digest)
+	}
+
+	// Cambiar un monto sí cambia el resumen
+	changed := slices.Clone(certs)
+	changed[2] = Certificate{ID: 3, Amount: 91, Orders: []Order{{ID: 4, Amount: 91, MerchantID: 3}}}
+	if DigestCertificates(changed) == digest {
+		t.Error("a changed amount produced the same digest")
+	}
+}
+
+func TestMissingOrdersReportsOmittedOrder(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 100, MerchantID: 1},
+		{ID: 2, Amount: 200, MerchantID: 2},
+		{ID: 3, Amount: 300, MerchantID: 1},
+		{ID: 4, Amount: 50, MerchantID: 3},
+	}
+	// La orden 3 quedó fuera a propósito
+	certs := []Certificate{
+		{ID: 1, Amount: 300, Orders: []Order{orders[0], orders[1]}},
+		{ID: 2, Amount: 50, Orders: []Order{orders[3]}},
+	}
+
+	missing := MissingOrders(orders, certs)
+	if len(missing) != 1 || missing[0].ID != 3 {
+		t.Errorf("MissingOrders = %+v, want only order 3", missing)
+	}
+	if err := VerifyCertificates(orders, certs, 1000); err == nil {
+		t.Error("VerifyCertificates accepted a packing with a missing order")
+	}
+
+	certs[1].Orders = append(certs[1].Orders, orders[2])
+	certs[1].Amount += orders[2].Amount
+	if missing := MissingOrders(orders, certs); len(missing) != 0 {
+		t.Errorf("MissingOrders after adding it back = %+v, want none", missing)
+	}
+}
+
+func TestValidateOrdersRejectsNonPositiveMerchant(t *testing.T) {
+	orders := []Order{
+		{ID: 1, Amount: 10, MerchantID: 3},
+		{ID: 42, Amount: 10, MerchantID: 0},
+		{ID: 43, Amount: 10, MerchantID: -1},
+	}
+	err := ValidateOrders(orders)
+	if err == nil {
+		t.Fatal("ValidateOrders accepted MerchantID 0")
+	}
+	if !strings.Contains(err.Error(), "orden 42") {
+		t.Errorf("error %q does not name order 42", err)
+	}
+	if err := ValidateOrders(orders[2:]); err == nil {
+		t.Error("ValidateOrders accepted MerchantID -1")
+	}
+	if err := ValidateOrders(orders[:1]); err != nil {
+		t.Errorf("ValidateOrders rejected a valid order: %v", err)
+	}
+}
+
+func TestVerifyCertificatesErrorCategories(t *testing.T) {
+	const limit = 1000
+	a := Order{ID: 1, Amount: 600, MerchantID: 1}
+	b := Order{ID: 2, Amount: 300, MerchantID: 2}
+	huge := Order{ID: 3, Amount: 1200, MerchantID: 3}
+	sentinels := []error{ErrOrderExceedsLimit, ErrCertificateOverflow, ErrDuplicateOrderID, ErrConservationFailed}
+
+	tests := []struct {
+		name   string
+		orders []Order
+		certs  []Certificate
+		want   error
+	}{
+		{"order exceeds limit", []Order{huge}, []Certificate{
+			{ID: 1, Amount: 1200, Orders: []Order{huge}},
+		}, ErrOrderExceedsLimit},
+		{"certificate overflow", []Order{a, b, {ID: 4, Amount: 200, MerchantID: 1}}, []Certificate{
+			{ID: 1, Amount: 1100, Orders: []Order{a, b, {ID: 4, Amount: 200, MerchantID: 1}}},
+		}, ErrCertificateOverflow},
+		{"duplicate order", []Order{a, b}, []Certificate{
+			{ID: 1, Amount: 900, Orders: []Order{a, b}},
+			{ID: 2, Amount: 300, Orders: []Order{b}},
+		}, ErrDuplicateOrderID},
+		{"missing order", []Order{a, b}, []Certificate{
+			{ID: 1, Amount: 600, Orders: []Order{a}},
+		}, ErrConservationFailed},
+		{"wrong amount", []Order{a, b}, []Certificate{
+			{ID: 1, Amount: 950, Orders: []Order{a, b}},
+		}, ErrConservationFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyCertificates(tt.orders, tt.certs, limit)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyCertificates = %v, want it to wrap %v", err, tt.want)
+			}
+			// Cada error pertenece a una sola categoría
+			for _, other := range sentinels {
+				if other != tt.want && errors.Is(err, other) {
+					t.Errorf("%v also matches %v", err, other)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateCertificateJSONStrict(t *testing.T) {
+	var valid bytes.Buffer
+	certs := []Certificate{
+		{ID: 1, Amount: 150, Orders: []Order{{ID: 1, Amount: 100, MerchantID: 1}, {ID: 2, Amount: 50, MerchantID: 2}}},
+		{ID: 2, Amount: 0, Orders: []Order{}},
+	}
+	if err := WriteCertificatesJSON(&valid, certs); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCertificateJSON(&valid); err != nil {
+		t.Fatalf("rejected the output of WriteCertificatesJSON: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"unexpected certificate field", `[{"ID": 1, "Amount": 10, "Orders": [], "Partner": "acme"}]`},
+		{"unexpected order field", `[{"ID": 1, "Amount": 10, "Orders": [{"ID": 1, "Amount": 10, "MerchantID": 1, "Fee": 2}]}]`},
+		{"missing amount", `[{"ID": 1, "Orders": []}]`},
+		{"missing orders", `[{"ID": 1, "Amount": 10}]`},
+		{"missing merchant", `[{"ID": 1, "Amount": 10, "Orders": [{"ID": 1, "Amount": 10}]}]`},
+		{"wrong type", `[{"ID": "1", "Amount": 10, "Orders": []}]`},
+		{"negative amount", `[{"ID": 1, "Amount": -10, "Orders": []}]`},
+		{"negative order amount", `[{"ID": 1, "Amount": 0, "Orders": [{"ID": 1, "Amount": -5, "MerchantID": 1}]}]`},
+		{"trailing data", `[] []`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCertificateJSON(strings.NewReader(tt.json)); err == nil {
+				t.Errorf("accepted %s", tt.json)
+			}
+		})
+	}
+}